@@ -1,20 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 	v1coreinformerfactory "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
@@ -24,51 +33,147 @@ import (
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/config"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/controller"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/delegates"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/health"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/journal"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/metrics"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/tracing"
 )
 
 const (
 	ErrorLoadingConfig int = iota + 1
 	ErrorBuildingController
+	ErrorValidatingFlags
+	ErrorSettingUpTracing
 )
 
+const tracingShutdownTimeout = 5 * time.Second
+
+const nodeNameEnvVariable = "NODE_NAME"
+
 func main() {
 	klog.InitFlags(nil)
 	configFilePath := flag.String(
 		"config",
 		config.DefaultDynamicNetworksControllerConfigFile,
 		"Specify the path to the multus-daemon configuration")
+	kubeconfig := flag.String(
+		"kubeconfig",
+		"",
+		"The path to a kubeconfig. Only required when running out of the cluster")
+	masterURL := flag.String(
+		"master",
+		"",
+		"The address of the Kubernetes API server. Overrides any value in the kubeconfig")
+	nodeName := flag.String(
+		"node-name",
+		os.Getenv(nodeNameEnvVariable),
+		fmt.Sprintf("The name of the node whose pods are reconciled. Defaults to the %s environment variable", nodeNameEnvVariable))
 
 	flag.Parse()
 
-	klog.Infof("dynamic-networks-controller: built from [%s]", controllerVersion())
+	if err := validateNodeName(*nodeName); err != nil {
+		klog.ErrorS(err, "invalid flags")
+		os.Exit(ErrorValidatingFlags)
+	}
 
 	controllerConfig, err := config.LoadConfig(*configFilePath)
 	if err != nil {
-		klog.Errorf("failed to load the multus-daemon configuration: %v", err)
+		klog.ErrorS(err, "failed to load the multus-daemon configuration")
 		os.Exit(ErrorLoadingConfig)
 	}
+	if controllerConfig.LogFormat == config.LogFormatJSON {
+		logging.UseJSON(os.Stderr, verbosity())
+	}
 
+	klog.InfoS("dynamic-networks-controller started", "version", controllerVersion())
+
+	if controllerConfig.TracingEndpoint != "" {
+		shutdownTracing, err := tracing.Setup(context.Background(), controllerConfig.TracingEndpoint)
+		if err != nil {
+			klog.ErrorS(err, "failed to set up the tracing")
+			os.Exit(ErrorSettingUpTracing)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				klog.ErrorS(err, "failed to flush the pending spans")
+			}
+		}()
+	}
+
+	// the stop channel is closed - once - for all its consumers to observe the shutdown
 	stopChannel := make(chan struct{})
+	stop := sync.OnceFunc(func() { close(stopChannel) })
+	controllerMetrics := metrics.New()
+	probes := health.NewChecker()
 
-	podNetworksController, err := newController(stopChannel, controllerConfig)
+	podNetworksController, err := newController(
+		stopChannel,
+		controllerConfig,
+		clusterAccess{kubeconfig: *kubeconfig, masterURL: *masterURL, nodeName: *nodeName},
+		controllerMetrics,
+		probes,
+	)
 	if err != nil {
-		klog.Errorf("failed to instantiate the %s controller: %v", controller.AdvertisedName, err)
-		close(stopChannel) // deferred calls will not be called after os.Exit is called
+		klog.ErrorS(err, "failed to instantiate the controller", "controller", controller.AdvertisedName)
+		stop() // deferred calls will not be called after os.Exit is called
 		os.Exit(ErrorBuildingController)
 	}
 
-	defer close(stopChannel)
-	handleSignals(stopChannel, os.Interrupt)
+	if controllerConfig.MetricsListenAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", controllerMetrics.Handler())
+		go listenAndServe(controllerConfig.MetricsListenAddress, mux)
+	}
+
+	if controllerConfig.HealthProbeListenAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", probes.LivenessHandler())
+		mux.Handle("/readyz", probes.ReadinessHandler())
+		go listenAndServe(controllerConfig.HealthProbeListenAddress, mux)
+	}
+
+	defer stop()
+	handleSignals(stop, os.Interrupt, syscall.SIGTERM)
 	podNetworksController.Start(stopChannel)
 }
 
-func newController(stopChannel chan struct{}, configuration *config.Multus) (*controller.PodNetworksController, error) {
-	klog.V(logging.Debug).Infof("creating pod update controller ...")
-	cfg, err := rest.InClusterConfig()
+// clusterAccess holds how the controller connects to the cluster, and which node it serves.
+type clusterAccess struct {
+	kubeconfig string
+	masterURL  string
+	nodeName   string
+}
+
+func validateNodeName(nodeName string) error {
+	if nodeName == "" {
+		return fmt.Errorf(
+			"the node name is empty: set it using the -node-name flag, or the %s environment variable",
+			nodeNameEnvVariable,
+		)
+	}
+	if errs := validation.IsDNS1123Subdomain(nodeName); len(errs) > 0 {
+		return fmt.Errorf("invalid node name %q: %s", nodeName, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func newController(
+	stopChannel chan struct{},
+	configuration *config.Multus,
+	access clusterAccess,
+	controllerMetrics *metrics.Metrics,
+	probes *health.Checker,
+) (*controller.PodNetworksController, error) {
+	klog.V(logging.Debug).InfoS("creating pod update controller")
+	// without a kubeconfig nor a master URL, the in-cluster configuration is used
+	cfg, err := clientcmd.BuildConfigFromFlags(access.masterURL, access.kubeconfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to implicitly generate the kubeconfig: %w", err)
+		return nil, fmt.Errorf("failed to build the kubeconfig: %w", err)
 	}
 
 	k8sClient, err := kubernetes.NewForConfig(cfg)
@@ -83,7 +188,7 @@ func newController(stopChannel chan struct{}, configuration *config.Multus) (*co
 
 	const noResyncPeriod = 0
 	podInformerFactory := v1coreinformerfactory.NewSharedInformerFactoryWithOptions(
-		k8sClient, noResyncPeriod, listenOnCoLocatedNode())
+		k8sClient, noResyncPeriod, listenOnCoLocatedNode(access.nodeName))
 
 	nadInformerFactory := nadinformers.NewSharedInformerFactory(nadClientSet, noResyncPeriod)
 
@@ -94,6 +199,68 @@ func newController(stopChannel chan struct{}, configuration *config.Multus) (*co
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CRI runtime (%s): %v", configuration.CriSocketPath, err)
 	}
+	sandboxCache := cri.NewSandboxCache(containerRuntime, time.Duration(configuration.SandboxResyncPeriodSeconds)*time.Second)
+
+	multusClient, multusClientName := newMultusClient(configuration)
+
+	controllerOpts := []controller.Option{
+		controller.WithMetrics(controllerMetrics),
+		controller.WithLivenessWindow(time.Duration(configuration.LivenessWindowSeconds) * time.Second),
+		controller.WithWorkers(configuration.Workers),
+		controller.WithShutdownGracePeriod(time.Duration(configuration.ShutdownGracePeriodSeconds) * time.Second),
+		controller.WithDelegateTimeouts(
+			time.Duration(configuration.DelegateAddTimeoutSeconds)*time.Second,
+			time.Duration(configuration.DelegateDelTimeoutSeconds)*time.Second,
+		),
+		controller.WithResyncPeriod(time.Duration(configuration.ResyncPeriodSeconds) * time.Second),
+	}
+	for _, errorClass := range controller.ErrorClasses {
+		controllerOpts = append(controllerOpts, controller.WithRetryPolicy(errorClass, retryPolicy(configuration, errorClass)))
+	}
+	if configuration.JournalDir != "" {
+		operationsJournal, err := journal.New(configuration.JournalDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the operations journal: %v", err)
+		}
+		controllerOpts = append(controllerOpts, controller.WithJournal(operationsJournal))
+	}
+	if configuration.DelegateConfigDir != "" {
+		delegateConfigs, err := delegates.New(configuration.DelegateConfigDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the delegate configurations store: %v", err)
+		}
+		controllerOpts = append(controllerOpts, controller.WithDelegateConfigStore(delegateConfigs))
+	}
+	if configuration.DriftAuditPeriodSeconds > 0 {
+		controllerOpts = append(
+			controllerOpts,
+			controller.WithDriftAudit(time.Duration(configuration.DriftAuditPeriodSeconds)*time.Second, configuration.RepairDrift),
+		)
+	}
+	controllerOpts = append(
+		controllerOpts,
+		controller.WithAttachmentCheck(
+			time.Duration(configuration.AttachmentCheckPeriodSeconds)*time.Second,
+			configuration.ReplugFailedChecks,
+		),
+	)
+	if configuration.GarbageCollectionPeriodSeconds > 0 {
+		controllerOpts = append(
+			controllerOpts,
+			controller.WithGarbageCollection(
+				time.Duration(configuration.GarbageCollectionPeriodSeconds)*time.Second,
+				access.nodeName,
+			),
+		)
+	}
+	if configuration.CheckPluginStatus {
+		controllerOpts = append(controllerOpts, controller.WithPluginStatusCheck())
+	}
+	if configuration.ProtectNetAttachDefsInUse {
+		controllerOpts = append(controllerOpts, controller.WithNetAttachDefProtection(access.nodeName))
+	} else {
+		controllerOpts = append(controllerOpts, controller.WithoutNetAttachDefProtection(access.nodeName))
+	}
 
 	podNetworksController, err := controller.NewPodNetworksController(
 		podInformerFactory,
@@ -102,30 +269,69 @@ func newController(stopChannel chan struct{}, configuration *config.Multus) (*co
 		newEventRecorder(eventBroadcaster),
 		k8sClient,
 		nadClientSet,
-		containerRuntime,
-		multuscni.NewClient(configuration.MultusSocketPath))
+		sandboxCache,
+		multusClient,
+		controllerOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the pod networks controller: %v", err)
 	}
 
-	klog.V(logging.Debug).Infof("starting informer factories ...")
+	probes.AddLivenessCheck("workers", podNetworksController.WorkersAlive)
+	probes.AddReadinessCheck("informers", podNetworksController.CachesSynced)
+	probes.AddReadinessCheck("cri", sandboxCache.Healthz)
+	probes.AddReadinessCheck(multusClientName, multusClient.Healthz)
+
+	klog.V(logging.Debug).InfoS("starting the pod sandboxes cache")
+	sandboxCache.Start(wait.ContextForChannel(stopChannel))
+
+	klog.V(logging.Debug).InfoS("starting informer factories")
 	podInformerFactory.Start(stopChannel)
 	nadInformerFactory.Start(stopChannel)
 
-	klog.V(logging.Debug).Infof("finished creating the pod networks controller")
+	klog.V(logging.Debug).InfoS("finished creating the pod networks controller")
 	return podNetworksController, nil
 }
 
-func listenOnCoLocatedNode() v1coreinformerfactory.SharedInformerOption {
+// delegateClient invokes the CNI plugins, and reports whether it can
+type delegateClient interface {
+	multuscni.Client
+	Healthz(ctx context.Context) error
+}
+
+// newMultusClient returns the client invoking the CNI plugins per the configured mode, along with the
+// name of its readiness check.
+func newMultusClient(configuration *config.Multus) (delegateClient, string) {
+	if configuration.CNIInvocationMode == config.CNIInvocationDirect {
+		return multuscni.NewDirectClient(configuration.CNIBinDir, configuration.CNIConfDir, configuration.CNICacheDir), "cni-plugins"
+	}
+	return multuscni.NewClient(configuration.MultusSocketPath), "multus"
+}
+
+// retryPolicy overrides the default retry policy of the error class with the configured one.
+func retryPolicy(configuration *config.Multus, errorClass controller.ErrorClass) controller.RetryPolicy {
+	policy := controller.DefaultRetryPolicy(errorClass)
+	policy.MaxDelay = time.Duration(configuration.MaxRetryBackoffSeconds) * time.Second
+
+	configuredPolicy := configuration.RetryPolicies[string(errorClass)]
+	if configuredPolicy.MaxRetries != nil {
+		policy.MaxRetries = *configuredPolicy.MaxRetries
+	}
+	if configuredPolicy.BaseBackoffMilliseconds > 0 {
+		policy.BaseDelay = time.Duration(configuredPolicy.BaseBackoffMilliseconds) * time.Millisecond
+	}
+	if configuredPolicy.MaxBackoffSeconds > 0 {
+		policy.MaxDelay = time.Duration(configuredPolicy.MaxBackoffSeconds) * time.Second
+	}
+	return policy
+}
+
+func listenOnCoLocatedNode(nodeName string) v1coreinformerfactory.SharedInformerOption {
 	return v1coreinformerfactory.WithTweakListOptions(
 		func(options *v1.ListOptions) {
-			const (
-				nodeNameEnvVariable = "NODE_NAME"
-			)
 			// The selector for the pods that this controller instance will watch/reconcile
 			selectorSet := fields.Set{
 				// select pods scheduled only on the node on which this controller instance is running
-				"spec.nodeName": os.Getenv(nodeNameEnvVariable),
+				"spec.nodeName": nodeName,
 				// select pods with a phase Running to avoid interfering with the cni-plugin works
 				// when pods got created/deleted
 				// see https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle/#pod-phase
@@ -137,7 +343,7 @@ func listenOnCoLocatedNode() v1coreinformerfactory.SharedInformerOption {
 
 func newEventBroadcaster(k8sClientset kubernetes.Interface) record.EventBroadcaster {
 	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(klog.Infof)
+	eventBroadcaster.StartStructuredLogging(0)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClientset.CoreV1().Events(v1.NamespaceAll)})
 	return eventBroadcaster
 }
@@ -146,12 +352,27 @@ func newEventRecorder(broadcaster record.EventBroadcaster) record.EventRecorder
 	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controller.AdvertisedName})
 }
 
-func handleSignals(stopChannel chan struct{}, signals ...os.Signal) {
+func listenAndServe(listenAddress string, handler http.Handler) {
+	const readHeaderTimeout = 5 * time.Second
+	server := &http.Server{
+		Addr:              listenAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	klog.InfoS("serving HTTP", "address", listenAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		klog.ErrorS(err, "failed to serve HTTP", "address", listenAddress)
+	}
+}
+
+// handleSignals calls stop once any of the signals is received.
+func handleSignals(stop func(), signals ...os.Signal) {
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, signals...)
 	go func() {
 		<-signalChannel
-		stopChannel <- struct{}{}
+		stop()
 	}()
 }
 
@@ -161,3 +382,12 @@ func controllerVersion() string {
 	}
 	return "SHA-NOT-FOUND"
 }
+
+// verbosity returns the klog verbosity, as set by the -v flag
+func verbosity() int {
+	level, err := strconv.Atoi(flag.Lookup("v").Value.String())
+	if err != nil {
+		return 0
+	}
+	return level
+}
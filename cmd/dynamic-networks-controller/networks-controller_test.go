@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDynamicNetworksController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamic networks controller command suite")
+}
+
+var _ = Describe("The signals handling", func() {
+	It("stops every consumer of the stop channel", func() {
+		const consumers = 3
+		stopChannel := make(chan struct{})
+		stop := sync.OnceFunc(func() { close(stopChannel) })
+		DeferCleanup(stop)
+
+		stopped := make(chan struct{}, consumers)
+		for i := 0; i < consumers; i++ {
+			go func() {
+				<-stopChannel
+				stopped <- struct{}{}
+			}()
+		}
+
+		handleSignals(stop, syscall.SIGUSR1)
+		Expect(syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)).To(Succeed())
+		for i := 0; i < consumers; i++ {
+			Eventually(stopped).Should(Receive())
+		}
+	})
+})
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
+	nadinformers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/informers/externalversions"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/webhook"
+)
+
+const (
+	ErrorBuildingWebhook int = iota + 1
+	ErrorServingWebhook
+)
+
+func main() {
+	klog.InitFlags(nil)
+	listenAddress := flag.String("listen-address", ":8443", "The address (host:port) on which the webhook is served")
+	certFile := flag.String("tls-cert-file", "/etc/webhook/certs/tls.crt", "The path to the webhook TLS certificate")
+	keyFile := flag.String("tls-private-key-file", "/etc/webhook/certs/tls.key", "The path to the webhook TLS private key")
+	flag.Parse()
+
+	stopChannel := make(chan struct{})
+	defer close(stopChannel)
+
+	validator, err := newValidator(stopChannel)
+	if err != nil {
+		klog.ErrorS(err, "failed to instantiate the pod networks validator")
+		close(stopChannel) // deferred calls will not be called after os.Exit is called
+		os.Exit(ErrorBuildingWebhook)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", validator)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	const readHeaderTimeout = 5 * time.Second
+	server := &http.Server{
+		Addr:              *listenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	go func() {
+		signalChannel := make(chan os.Signal, 1)
+		signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
+		<-signalChannel
+		if err := server.Close(); err != nil {
+			klog.ErrorS(err, "failed to stop serving the webhook")
+		}
+	}()
+
+	klog.InfoS("serving the pod networks validating webhook", "address", *listenAddress)
+	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		klog.ErrorS(err, "failed to serve the webhook")
+		close(stopChannel)
+		os.Exit(ErrorServingWebhook)
+	}
+}
+
+func newValidator(stopChannel chan struct{}) (*webhook.PodNetworksValidator, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to implicitly generate the kubeconfig: %w", err)
+	}
+
+	nadClientSet, err := nadclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the net-attach-def client: %v", err)
+	}
+
+	const noResyncPeriod = 0
+	nadInformerFactory := nadinformers.NewSharedInformerFactory(nadClientSet, noResyncPeriod)
+	nadInformer := nadInformerFactory.K8sCniCncfIo().V1().NetworkAttachmentDefinitions()
+	validator := webhook.NewPodNetworksValidator(nadInformer.Lister())
+
+	nadInformerFactory.Start(stopChannel)
+	if !cache.WaitForCacheSync(stopChannel, nadInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed waiting for the network-attachment-definitions cache to sync")
+	}
+	return validator, nil
+}
@@ -0,0 +1,86 @@
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// DynamicAttachmentsAnnot is the controller owned pod annotation recording the
+// attachments the controller plugged into the pod.
+const DynamicAttachmentsAnnot = "dynamic-networks-controller.k8s.cni.cncf.io/attachments"
+
+// DynamicAttachment records the network selection element an attachment was plugged with,
+// along with the digest of the network-attachment-definition configuration it used, the
+// digest of the delegate configuration it was plugged with - indexing its on-host copy,
+// required to remove it once the network-attachment-definition is gone - and the ID of
+// the pod sandbox it was plugged into.
+type DynamicAttachment struct {
+	NetworkSelectionElement nadv1.NetworkSelectionElement `json:"networkSelectionElement"`
+	NetAttachDefDigest      string                        `json:"netAttachDefDigest,omitempty"`
+	DelegateConfigDigest    string                        `json:"delegateConfigDigest,omitempty"`
+	SandboxID               string                        `json:"sandboxID,omitempty"`
+}
+
+func PodDynamicAttachments(pod *corev1.Pod) ([]DynamicAttachment, error) {
+	var dynamicAttachments []DynamicAttachment
+	if dynamicAttachmentsString, wasFound := pod.GetAnnotations()[DynamicAttachmentsAnnot]; wasFound {
+		if err := json.Unmarshal([]byte(dynamicAttachmentsString), &dynamicAttachments); err != nil {
+			return nil, fmt.Errorf("could not unmarshall the dynamic attachments of pod %s: %v", podNameAndNs(pod), err)
+		}
+	}
+	return dynamicAttachments, nil
+}
+
+func IndexDynamicAttachments(dynamicAttachments []DynamicAttachment) map[string]DynamicAttachment {
+	indexedDynamicAttachments := make(map[string]DynamicAttachment, len(dynamicAttachments))
+	for i := range dynamicAttachments {
+		indexedDynamicAttachments[NetworkSelectionElementIndexKey(dynamicAttachments[i].NetworkSelectionElement)] = dynamicAttachments[i]
+	}
+	return indexedDynamicAttachments
+}
+
+// UpdatePodDynamicAttachments computes the dynamic attachments of the pod once
+// the attachment results - processed in order - are applied: a result holding a
+// CNI response - or relocating its attachment - records it, a result without
+// response forgets it.
+func UpdatePodDynamicAttachments(currentPod *corev1.Pod, attachmentResults []AttachmentResult) ([]DynamicAttachment, error) {
+	dynamicAttachments, err := PodDynamicAttachments(currentPod)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachmentResult := range attachmentResults {
+		if !attachmentResult.IsValid() {
+			continue
+		}
+		dynamicAttachments = forgetDynamicAttachment(dynamicAttachments, *attachmentResult.attachment)
+		if attachmentResult.HasResult() || attachmentResult.relocated {
+			dynamicAttachments = append(dynamicAttachments, DynamicAttachment{
+				NetworkSelectionElement: *attachmentResult.attachment,
+				NetAttachDefDigest:      attachmentResult.netAttachDefDigest,
+				DelegateConfigDigest:    attachmentResult.delegateConfigDigest,
+				SandboxID:               attachmentResult.sandboxID,
+			})
+		}
+	}
+
+	if dynamicAttachments == nil {
+		dynamicAttachments = make([]DynamicAttachment, 0)
+	}
+	return dynamicAttachments, nil
+}
+
+func forgetDynamicAttachment(dynamicAttachments []DynamicAttachment, attachment nadv1.NetworkSelectionElement) []DynamicAttachment {
+	attachmentKey := NetworkSelectionElementIndexKey(attachment)
+	remainingAttachments := dynamicAttachments[:0]
+	for i := range dynamicAttachments {
+		if NetworkSelectionElementIndexKey(dynamicAttachments[i].NetworkSelectionElement) != attachmentKey {
+			remainingAttachments = append(remainingAttachments, dynamicAttachments[i])
+		}
+	}
+	return remainingAttachments
+}
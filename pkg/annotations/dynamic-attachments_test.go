@@ -0,0 +1,104 @@
+package annotations_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+)
+
+var _ = Describe("UpdatePodDynamicAttachments", func() {
+	const (
+		namespace   = "ns1"
+		networkName = "tenantnetwork"
+		podName     = "tpod"
+	)
+
+	var result *api.Response
+
+	BeforeEach(func() {
+		result = &api.Response{}
+	})
+
+	newAttachment := func(ifaceName string, mac string) nadv1.NetworkSelectionElement {
+		return nadv1.NetworkSelectionElement{
+			Name:             networkName,
+			Namespace:        namespace,
+			InterfaceRequest: ifaceName,
+			MacRequest:       mac,
+		}
+	}
+
+	podWithDynamicAttachments := func(attachments ...nadv1.NetworkSelectionElement) []annotations.DynamicAttachment {
+		dynamicAttachments := make([]annotations.DynamicAttachment, 0, len(attachments))
+		for _, attachment := range attachments {
+			dynamicAttachments = append(dynamicAttachments, annotations.DynamicAttachment{NetworkSelectionElement: attachment})
+		}
+		return dynamicAttachments
+	}
+
+	updatedDynamicAttachments := func(current []annotations.DynamicAttachment, results ...annotations.AttachmentResult) []annotations.DynamicAttachment {
+		pod := newPod(podName, namespace)
+		serializedAttachments, err := json.Marshal(current)
+		Expect(err).NotTo(HaveOccurred())
+		pod.Annotations[annotations.DynamicAttachmentsAnnot] = string(serializedAttachments)
+
+		dynamicAttachments, err := annotations.UpdatePodDynamicAttachments(pod, results)
+		Expect(err).NotTo(HaveOccurred())
+		return dynamicAttachments
+	}
+
+	It("records the added attachments", func() {
+		attachment := newAttachment("iface1", "02:03:04:05:06:07")
+		Expect(
+			updatedDynamicAttachments(nil, *annotations.NewAttachmentResult(&attachment, result)),
+		).To(Equal(podWithDynamicAttachments(attachment)))
+	})
+
+	It("forgets the removed attachments", func() {
+		attachment := newAttachment("iface1", "02:03:04:05:06:07")
+		Expect(
+			updatedDynamicAttachments(
+				podWithDynamicAttachments(attachment),
+				*annotations.NewAttachmentResult(&attachment, nil),
+			),
+		).To(BeEmpty())
+	})
+
+	It("records the latest attributes of a re-plugged attachment", func() {
+		previousAttachment := newAttachment("iface1", "02:03:04:05:06:07")
+		otherAttachment := newAttachment("iface2", "")
+		updatedAttachment := newAttachment("iface1", "02:03:04:05:06:08")
+		Expect(
+			updatedDynamicAttachments(
+				podWithDynamicAttachments(previousAttachment, otherAttachment),
+				*annotations.NewAttachmentResult(&previousAttachment, nil),
+				*annotations.NewAttachmentResult(&updatedAttachment, result),
+			),
+		).To(Equal(podWithDynamicAttachments(otherAttachment, updatedAttachment)))
+	})
+
+	It("records the relocated attachments with their new sandbox", func() {
+		attachment := newAttachment("iface1", "02:03:04:05:06:07")
+		dynamicAttachment := annotations.DynamicAttachment{
+			NetworkSelectionElement: attachment,
+			NetAttachDefDigest:      "digest",
+			SandboxID:               "1234",
+		}
+		Expect(
+			updatedDynamicAttachments(
+				[]annotations.DynamicAttachment{dynamicAttachment},
+				*annotations.NewRelocatedAttachmentResult(&dynamicAttachment, "5678"),
+			),
+		).To(Equal([]annotations.DynamicAttachment{{
+			NetworkSelectionElement: attachment,
+			NetAttachDefDigest:      "digest",
+			SandboxID:               "5678",
+		}}))
+	})
+})
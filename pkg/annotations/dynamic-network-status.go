@@ -3,6 +3,7 @@ package annotations
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -30,20 +31,24 @@ func AddDynamicIfaceToStatus(currentPodNetworkStatus []nettypes.NetworkStatus, a
 	return currentPodNetworkStatus, nil
 }
 
+// DeleteDynamicIfaceFromStatus removes the interfaces of the network selection elements from the network
+// status; the remaining entries keep their relative order.
 func DeleteDynamicIfaceFromStatus(currentPodNetworkStatus []nettypes.NetworkStatus, networkSelectionElements ...nettypes.NetworkSelectionElement) ([]nettypes.NetworkStatus, error) {
-	indexedStatus := IndexNetworkStatus(currentPodNetworkStatus)
+	netStatusKeysToRemove := map[string]struct{}{}
 	for _, networkSelectionElement := range networkSelectionElements {
 		netStatusKey := fmt.Sprintf(
 			"%s/%s",
 			NamespacedName(networkSelectionElement.Namespace, networkSelectionElement.Name),
 			networkSelectionElement.InterfaceRequest,
 		)
-		delete(indexedStatus, netStatusKey)
+		netStatusKeysToRemove[netStatusKey] = struct{}{}
 	}
 
-	newIfaceStatus := make([]nettypes.NetworkStatus, 0)
-	for networkStatusKey := range indexedStatus {
-		newIfaceStatus = append(newIfaceStatus, indexedStatus[networkStatusKey])
+	newIfaceStatus := make([]nettypes.NetworkStatus, 0, len(currentPodNetworkStatus))
+	for i := range currentPodNetworkStatus {
+		if _, isRemoved := netStatusKeysToRemove[NetworkStatusIndexKey(currentPodNetworkStatus[i])]; !isRemoved {
+			newIfaceStatus = append(newIfaceStatus, currentPodNetworkStatus[i])
+		}
 	}
 
 	return newIfaceStatus, nil
@@ -104,30 +109,35 @@ func IndexNetworkStatusIgnoringDefaultNetwork(pod *corev1.Pod) map[string]nettyp
 	})
 }
 
+// UpdatePodNetworkStatus applies the attachment results - in order - to the pod network status:
+// a result holding a CNI response (re-)appends an interface, a result without response removes it,
+// while a relocated attachment keeps its interface.
+// The entries keep their relative order, while the default network one is listed first. Applying the
+// results to a status they were already applied to leaves it unchanged.
 func UpdatePodNetworkStatus(currentPod *corev1.Pod, attachmentsToUpdate []AttachmentResult) ([]nettypes.NetworkStatus, error) {
-	var (
-		toAdd    []AttachmentResult
-		toRemove []nettypes.NetworkSelectionElement
-	)
+	updatedNetworkStatus, err := PodDynamicNetworkStatus(currentPod)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, res := range attachmentsToUpdate {
-		if res.IsValid() && !res.HasResult() {
-			toRemove = append(toRemove, *res.attachment)
+		if !res.IsValid() || res.relocated {
+			continue
+		}
+		updatedNetworkStatus, err = DeleteDynamicIfaceFromStatus(updatedNetworkStatus, *res.attachment)
+		if err == nil && res.HasResult() {
+			updatedNetworkStatus, err = AddDynamicIfaceToStatus(updatedNetworkStatus, res)
+		}
+		if err != nil {
+			return nil, err
 		}
-		toAdd = append(toAdd, res)
 	}
 
-	currentIfaceStatus, err := PodDynamicNetworkStatus(currentPod)
-	if err != nil {
-		return nil, err
-	}
-	updatedNetworkStatus, err := AddDynamicIfaceToStatus(currentIfaceStatus, toAdd...)
-	if err != nil {
-		return nil, err
-	}
-	updatedNetworkStatus, err = DeleteDynamicIfaceFromStatus(updatedNetworkStatus, toRemove...)
-	if err != nil {
-		return nil, err
+	if updatedNetworkStatus == nil {
+		updatedNetworkStatus = make([]nettypes.NetworkStatus, 0)
 	}
+	sort.SliceStable(updatedNetworkStatus, func(i, j int) bool {
+		return updatedNetworkStatus[i].Default && !updatedNetworkStatus[j].Default
+	})
 	return updatedNetworkStatus, nil
 }
@@ -2,7 +2,10 @@ package annotations_test
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/rand"
 	"net"
+	"slices"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -247,6 +250,94 @@ var _ = Describe("NetworkStatusFromResponse", func() {
 				networkName: "net2",
 			},
 		))
+
+	Describe("the network status order", func() {
+		const (
+			numSequences  = 200
+			maxUpdates    = 20
+			maxResults    = 3
+			numInterfaces = 8
+		)
+
+		defaultNetworkStatus := nadv1.NetworkStatus{
+			Name:      annotations.NamespacedName(namespace, "default-network"),
+			Interface: "eth0",
+			Default:   true,
+		}
+
+		// randomResults returns the results of random additions and removals of the interfaces, applying
+		// them to the model: the keys of the network status entries, in the expected order.
+		randomResults := func(rng *rand.Rand, expectedKeys []string) ([]annotations.AttachmentResult, []string) {
+			var results []annotations.AttachmentResult
+			for range 1 + rng.Intn(maxResults) {
+				ifaceName := fmt.Sprintf("net%d", rng.Intn(numInterfaces))
+				attachment := newNetworkSelectionElementWithIface(networkName, ifaceName, namespace)
+				key := annotations.NetworkStatusIndexKey(nadv1.NetworkStatus{
+					Name:      annotations.NamespacedName(namespace, networkName),
+					Interface: ifaceName,
+				})
+				if slices.Contains(expectedKeys, key) {
+					results = append(results, *annotations.NewAttachmentResult(attachment, nil))
+					expectedKeys = slices.DeleteFunc(expectedKeys, func(expectedKey string) bool { return expectedKey == key })
+				} else {
+					results = append(results, *annotations.NewAttachmentResult(attachment, newResponse(ifaceName, macAddr)))
+					expectedKeys = append(expectedKeys, key)
+				}
+			}
+			return results, expectedKeys
+		}
+
+		statusKeys := func(networkStatus []nadv1.NetworkStatus) []string {
+			keys := make([]string, 0, len(networkStatus))
+			for i := range networkStatus {
+				keys = append(keys, annotations.NetworkStatusIndexKey(networkStatus[i]))
+			}
+			return keys
+		}
+
+		It("appends the added interfaces, and keeps the relative order of the remaining ones on removal", func() {
+			rng := rand.New(rand.NewSource(GinkgoRandomSeed()))
+			for range numSequences {
+				pod := newPod(podName, namespace, defaultNetworkStatus)
+				expectedKeys := []string{annotations.NetworkStatusIndexKey(defaultNetworkStatus)}
+				for range rng.Intn(maxUpdates) {
+					var results []annotations.AttachmentResult
+					results, expectedKeys = randomResults(rng, expectedKeys)
+
+					networkStatus, err := annotations.UpdatePodNetworkStatus(pod, results)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(statusKeys(networkStatus)).To(Equal(expectedKeys))
+					pod = newPod(podName, namespace, networkStatus...)
+				}
+			}
+		})
+
+		It("lists the default network first, whatever its position was", func() {
+			rng := rand.New(rand.NewSource(GinkgoRandomSeed()))
+			for range numSequences {
+				var initialStatus []nadv1.NetworkStatus
+				for i := range rng.Intn(numInterfaces) {
+					initialStatus = append(initialStatus, nadv1.NetworkStatus{
+						Name:      annotations.NamespacedName(namespace, "initial-network"),
+						Interface: fmt.Sprintf("initial%d", i),
+					})
+				}
+				initialStatus = slices.Insert(initialStatus, rng.Intn(len(initialStatus)+1), defaultNetworkStatus)
+
+				expectedKeys := []string{annotations.NetworkStatusIndexKey(defaultNetworkStatus)}
+				for i := range initialStatus {
+					if !initialStatus[i].Default {
+						expectedKeys = append(expectedKeys, annotations.NetworkStatusIndexKey(initialStatus[i]))
+					}
+				}
+				results, expectedKeys := randomResults(rng, expectedKeys)
+
+				networkStatus, err := annotations.UpdatePodNetworkStatus(newPod(podName, namespace, initialStatus...), results)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(statusKeys(networkStatus)).To(Equal(expectedKeys))
+			}
+		})
+	})
 })
 
 func newPod(podName string, namespace string, netStatus ...nadv1.NetworkStatus) *corev1.Pod {
@@ -32,7 +32,7 @@ import (
 func ParsePodNetworkAnnotations(podNetworks, defaultNamespace string) ([]*nadv1.NetworkSelectionElement, error) {
 	var networks []*nadv1.NetworkSelectionElement
 
-	klog.V(5).Infof("parsePodNetworkAnnotation: %s, %s", podNetworks, defaultNamespace)
+	klog.V(5).InfoS("parsePodNetworkAnnotation", "networks", podNetworks, "defaultNamespace", defaultNamespace)
 	if podNetworks == "" {
 		return nil, fmt.Errorf("parsePodNetworkAnnotation: pod annotation does not have \"network\" as key")
 	}
@@ -99,7 +99,7 @@ func parsePodNetworkObjectName(podnetwork string) (string, string, string, error
 	var netIfName string
 	var networkName string
 
-	klog.V(5).Infof("parsePodNetworkObjectName: %s", podnetwork)
+	klog.V(5).InfoS("parsePodNetworkObjectName", "network", podnetwork)
 	slashItems := strings.Split(podnetwork, "/")
 	if len(slashItems) == 2 {
 		netNsName = strings.TrimSpace(slashItems[0])
@@ -132,7 +132,12 @@ func parsePodNetworkObjectName(podnetwork string) (string, string, string, error
 		}
 	}
 
-	klog.V(5).Infof("parsePodNetworkObjectName: parsed: %s, %s, %s", netNsName, networkName, netIfName)
+	klog.V(5).InfoS(
+		"parsePodNetworkObjectName: parsed",
+		"namespace", netNsName,
+		"network", networkName,
+		"interface", netIfName,
+	)
 	return netNsName, networkName, netIfName, nil
 }
 
@@ -168,7 +173,7 @@ func networkSelectionElements(podAnnotations map[string]string, podNamespace str
 	}
 	podNetworkSelectionElements, err := ParsePodNetworkAnnotations(podNetworks, podNamespace)
 	if err != nil {
-		klog.Errorf("failed to extract the network selection elements: %v", err)
+		klog.ErrorS(err, "failed to extract the network selection elements")
 		return nil, err
 	}
 
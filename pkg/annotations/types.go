@@ -6,8 +6,13 @@ import (
 )
 
 type AttachmentResult struct {
-	attachment *nadv1.NetworkSelectionElement
-	result     *multusapi.Response
+	attachment           *nadv1.NetworkSelectionElement
+	result               *multusapi.Response
+	netAttachDefDigest   string
+	delegateConfigDigest string
+	sandboxID            string
+	// relocated is set for the attachments recorded anew with another sandbox, their interface untouched
+	relocated bool
 }
 
 func NewAttachmentResult(attachment *nadv1.NetworkSelectionElement, result *multusapi.Response) *AttachmentResult {
@@ -17,6 +22,19 @@ func NewAttachmentResult(attachment *nadv1.NetworkSelectionElement, result *mult
 	}
 }
 
+// NewRelocatedAttachmentResult returns the result of a dynamic attachment found plugged into another
+// sandbox than the one it was recorded with: it is recorded anew with the given sandbox ID, while
+// its network-status is left as is.
+func NewRelocatedAttachmentResult(dynamicAttachment *DynamicAttachment, sandboxID string) *AttachmentResult {
+	return &AttachmentResult{
+		attachment:           &dynamicAttachment.NetworkSelectionElement,
+		netAttachDefDigest:   dynamicAttachment.NetAttachDefDigest,
+		delegateConfigDigest: dynamicAttachment.DelegateConfigDigest,
+		sandboxID:            sandboxID,
+		relocated:            true,
+	}
+}
+
 func (ar *AttachmentResult) IsValid() bool {
 	return ar != nil && ar.attachment != nil
 }
@@ -24,3 +42,22 @@ func (ar *AttachmentResult) IsValid() bool {
 func (ar *AttachmentResult) HasResult() bool {
 	return ar.IsValid() && ar.result != nil
 }
+
+// WithNetAttachDefDigest sets the digest of the network-attachment-definition
+// configuration the attachment was plugged with.
+func (ar *AttachmentResult) WithNetAttachDefDigest(digest string) *AttachmentResult {
+	ar.netAttachDefDigest = digest
+	return ar
+}
+
+// WithDelegateConfigDigest sets the digest of the delegate configuration the attachment was plugged with.
+func (ar *AttachmentResult) WithDelegateConfigDigest(digest string) *AttachmentResult {
+	ar.delegateConfigDigest = digest
+	return ar
+}
+
+// WithSandboxID sets the ID of the pod sandbox the attachment was plugged into.
+func (ar *AttachmentResult) WithSandboxID(sandboxID string) *AttachmentResult {
+	ar.sandboxID = sandboxID
+	return ar
+}
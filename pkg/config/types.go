@@ -12,15 +12,163 @@ const (
 	DefaultDynamicNetworksControllerConfigFile = "/etc/cni/net.d/multus.d/daemon-config.json"
 	containerdSocketPath                       = "/run/containerd/containerd.sock"
 	defaultMultusSocketPath                    = "/var/run/multus-cni/multus.sock"
+	defaultLivenessWindowSeconds               = 300
+	defaultWorkers                             = 1
+	defaultShutdownGracePeriodSeconds          = 20
+	defaultMaxRetryBackoffSeconds              = 300
+	defaultDelegateAddTimeoutSeconds           = 60
+	defaultDelegateDelTimeoutSeconds           = 30
+	defaultCNIBinDir                           = "/opt/cni/bin"
+	defaultCNIConfDir                          = "/etc/cni/net.d"
+	defaultCNICacheDir                         = "/var/lib/cni"
+	defaultSandboxResyncPeriodSeconds          = 60
+)
+
+const (
+	// CNIInvocationMultus delegates the CNI requests to the multus server
+	CNIInvocationMultus = "multus"
+	// CNIInvocationDirect runs the CNI plugins binaries, without the multus server
+	CNIInvocationDirect = "direct"
+)
+
+const (
+	// LogFormatText logs the messages as klog text lines
+	LogFormatText = "text"
+	// LogFormatJSON logs the messages as JSON objects, one per line
+	LogFormatJSON = "json"
 )
 
 type Multus struct {
 	// path to the socket through which the controller will query the CRI
 	CriSocketPath string `json:"criSocketPath"`
 
+	// Period (in seconds) at which the pod sandboxes of the CRI runtime are listed
+	// anew; in between, the sandboxes are tracked using the CRI container events.
+	SandboxResyncPeriodSeconds int `json:"sandboxResyncPeriodSeconds,omitempty"`
+
 	// Points to the path of the unix domain socket through which the
 	// client communicates with the multus server.
 	MultusSocketPath string `json:"multusSocketPath"`
+
+	// How the CNI plugins are invoked: either through the multus server ("multus"),
+	// or directly ("direct"). Defaults to "multus".
+	CNIInvocationMode string `json:"cniInvocationMode,omitempty"`
+
+	// Colon-separated list of the directories of the CNI plugins binaries, when
+	// invoked directly.
+	CNIBinDir string `json:"cniBinDir,omitempty"`
+
+	// Directory of the CNI configurations referred to by name by the
+	// network-attachment-definitions, when the plugins are invoked directly.
+	CNIConfDir string `json:"cniConfDir,omitempty"`
+
+	// Directory of the CNI results cache, when the plugins are invoked directly.
+	CNICacheDir string `json:"cniCacheDir,omitempty"`
+
+	// Format of the controller logs: either "text" or "json". Defaults to "text".
+	LogFormat string `json:"logFormat,omitempty"`
+
+	// Address (host:port) of the OTLP gRPC collector the reconciliation traces are
+	// exported to. The tracing is disabled when empty.
+	TracingEndpoint string `json:"tracingEndpoint,omitempty"`
+
+	// Address (host:port) on which the prometheus metrics are served. The
+	// metrics endpoint is disabled when empty.
+	MetricsListenAddress string `json:"metricsListenAddress,omitempty"`
+
+	// Address (host:port) on which the /healthz and /readyz probes are served.
+	// The probes endpoints are disabled when empty.
+	HealthProbeListenAddress string `json:"healthProbeListenAddress,omitempty"`
+
+	// For how long (in seconds) each of the workers can go without processing any
+	// item while there is pending work before the liveness probe fails.
+	LivenessWindowSeconds int `json:"livenessWindowSeconds,omitempty"`
+
+	// Number of workers reconciling the pods in parallel. The reconciliation of
+	// a given pod is always performed by a single worker at a time.
+	Workers int `json:"workers,omitempty"`
+
+	// When set, the network-attachment-definitions in use by the dynamic attachments
+	// of the pods on the node are protected against deletion using a finalizer. When
+	// unset, the finalizer of the node is released from them.
+	ProtectNetAttachDefsInUse bool `json:"protectNetAttachDefsInUse,omitempty"`
+
+	// For how long (in seconds) the in-flight reconciliations are waited for on
+	// shutdown, before the in-flight and remaining CNI operations are aborted.
+	ShutdownGracePeriodSeconds int `json:"shutdownGracePeriodSeconds,omitempty"`
+
+	// Deadline (in seconds) of the ADD requests sent to the multus server.
+	DelegateAddTimeoutSeconds int `json:"delegateAddTimeoutSeconds,omitempty"`
+
+	// Deadline (in seconds) of the DEL requests sent to the multus server.
+	DelegateDelTimeoutSeconds int `json:"delegateDelTimeoutSeconds,omitempty"`
+
+	// Directory of the on-host journal of the CNI operations whose outcome is not yet
+	// recorded in the pods network-status. The journal is disabled when empty.
+	JournalDir string `json:"journalDir,omitempty"`
+
+	// Directory of the on-host store of the delegate configurations the interfaces were
+	// plugged with, used to remove them once their network-attachment-definition is
+	// deleted. The configurations are not stored when empty.
+	DelegateConfigDir string `json:"delegateConfigDir,omitempty"`
+
+	// Period (in seconds) of the audit comparing the interfaces of the pods network
+	// namespace with their network-status. The audit is disabled when 0.
+	DriftAuditPeriodSeconds int `json:"driftAuditPeriodSeconds,omitempty"`
+
+	// When set, the interfaces drifting from the pods network-status are repaired by
+	// the audit: the missing ones are re-added, and the requested orphans removed.
+	RepairDrift bool `json:"repairDrift,omitempty"`
+
+	// Period (in seconds) of the CNI CHECK of the attachments listed in the pods
+	// network-status. The periodic check is disabled when 0; the checks requested
+	// using the pod annotation are performed regardless.
+	AttachmentCheckPeriodSeconds int `json:"attachmentCheckPeriodSeconds,omitempty"`
+
+	// When set, the attachments failing their CNI CHECK are plugged anew.
+	ReplugFailedChecks bool `json:"replugFailedChecks,omitempty"`
+
+	// Period (in seconds) of the CNI GC of the network-attachment-definitions, which
+	// releases the resources of the attachments not in use by the pods of the node.
+	// The garbage collection is disabled when 0; it requires the direct CNI
+	// invocation mode.
+	GarbageCollectionPeriodSeconds int `json:"garbageCollectionPeriodSeconds,omitempty"`
+
+	// When set, the attachments of a network are only added once the CNI STATUS of
+	// its plugins reports they are ready; it requires the direct CNI invocation mode.
+	CheckPluginStatus bool `json:"checkPluginStatus,omitempty"`
+
+	// Period (in seconds) of the resync re-queuing the running pods whose dynamic
+	// attachments differ from the requested ones. The resync is disabled when 0.
+	ResyncPeriodSeconds int `json:"resyncPeriodSeconds,omitempty"`
+
+	// Upper bound (in seconds) of the exponential backoff the failed reconciliations
+	// are retried with, unless overridden by the retry policy of the error class.
+	MaxRetryBackoffSeconds int `json:"maxRetryBackoffSeconds,omitempty"`
+
+	// Retry policies of the reconciliations failing with a given class of errors,
+	// indexed by error class: NotFound, Transient, or Permanent.
+	RetryPolicies map[string]RetryPolicy `json:"retryPolicies,omitempty"`
+}
+
+// RetryPolicy overrides the defaults of the retry policy of an error class.
+type RetryPolicy struct {
+	// Number of times the failed reconciliations are retried; -1 retries them until
+	// they succeed.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+
+	// Initial delay (in milliseconds) of the exponential backoff.
+	BaseBackoffMilliseconds int `json:"baseBackoffMilliseconds,omitempty"`
+
+	// Upper bound (in seconds) of the exponential backoff.
+	MaxBackoffSeconds int `json:"maxBackoffSeconds,omitempty"`
+}
+
+// the error classes the retry policies are defined for
+var errorClasses = map[string]struct{}{
+	"NotFound":  {},
+	"Transient": {},
+	"Permanent": {},
 }
 
 // LoadConfig loads the configuration for the multus daemon
@@ -43,5 +191,130 @@ func LoadConfig(configPath string) (*Multus, error) {
 		daemonNetConf.CriSocketPath = containerdSocketPath
 	}
 
+	switch daemonNetConf.CNIInvocationMode {
+	case "":
+		daemonNetConf.CNIInvocationMode = CNIInvocationMultus
+	case CNIInvocationMultus:
+	case CNIInvocationDirect:
+		if daemonNetConf.CNIBinDir == "" {
+			daemonNetConf.CNIBinDir = defaultCNIBinDir
+		}
+		if daemonNetConf.CNIConfDir == "" {
+			daemonNetConf.CNIConfDir = defaultCNIConfDir
+		}
+		if daemonNetConf.CNICacheDir == "" {
+			daemonNetConf.CNICacheDir = defaultCNICacheDir
+		}
+	default:
+		return nil, fmt.Errorf("invalid CNI invocation mode: %q", daemonNetConf.CNIInvocationMode)
+	}
+
+	switch daemonNetConf.LogFormat {
+	case "":
+		daemonNetConf.LogFormat = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid log format: %q", daemonNetConf.LogFormat)
+	}
+
+	if daemonNetConf.LivenessWindowSeconds == 0 {
+		daemonNetConf.LivenessWindowSeconds = defaultLivenessWindowSeconds
+	}
+
+	if daemonNetConf.Workers < 0 {
+		return nil, fmt.Errorf("invalid number of workers: %d", daemonNetConf.Workers)
+	}
+
+	if daemonNetConf.Workers == 0 {
+		daemonNetConf.Workers = defaultWorkers
+	}
+
+	if daemonNetConf.ShutdownGracePeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid shutdown grace period: %d", daemonNetConf.ShutdownGracePeriodSeconds)
+	}
+
+	if daemonNetConf.ShutdownGracePeriodSeconds == 0 {
+		daemonNetConf.ShutdownGracePeriodSeconds = defaultShutdownGracePeriodSeconds
+	}
+
+	if daemonNetConf.DelegateAddTimeoutSeconds < 0 {
+		return nil, fmt.Errorf("invalid delegate ADD timeout: %d", daemonNetConf.DelegateAddTimeoutSeconds)
+	}
+
+	if daemonNetConf.DelegateAddTimeoutSeconds == 0 {
+		daemonNetConf.DelegateAddTimeoutSeconds = defaultDelegateAddTimeoutSeconds
+	}
+
+	if daemonNetConf.DelegateDelTimeoutSeconds < 0 {
+		return nil, fmt.Errorf("invalid delegate DEL timeout: %d", daemonNetConf.DelegateDelTimeoutSeconds)
+	}
+
+	if daemonNetConf.DelegateDelTimeoutSeconds == 0 {
+		daemonNetConf.DelegateDelTimeoutSeconds = defaultDelegateDelTimeoutSeconds
+	}
+
+	if daemonNetConf.DriftAuditPeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid drift audit period: %d", daemonNetConf.DriftAuditPeriodSeconds)
+	}
+
+	if daemonNetConf.AttachmentCheckPeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid attachment check period: %d", daemonNetConf.AttachmentCheckPeriodSeconds)
+	}
+
+	if daemonNetConf.GarbageCollectionPeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid garbage collection period: %d", daemonNetConf.GarbageCollectionPeriodSeconds)
+	}
+
+	// the multus server delegate endpoint only serves ADD, DEL and CHECK requests
+	if daemonNetConf.GarbageCollectionPeriodSeconds > 0 && daemonNetConf.CNIInvocationMode != CNIInvocationDirect {
+		return nil, fmt.Errorf("the garbage collection requires the %q CNI invocation mode", CNIInvocationDirect)
+	}
+
+	if daemonNetConf.CheckPluginStatus && daemonNetConf.CNIInvocationMode != CNIInvocationDirect {
+		return nil, fmt.Errorf("the plugin status check requires the %q CNI invocation mode", CNIInvocationDirect)
+	}
+
+	if daemonNetConf.ResyncPeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid resync period: %d", daemonNetConf.ResyncPeriodSeconds)
+	}
+
+	if daemonNetConf.SandboxResyncPeriodSeconds < 0 {
+		return nil, fmt.Errorf("invalid sandbox resync period: %d", daemonNetConf.SandboxResyncPeriodSeconds)
+	}
+
+	if daemonNetConf.SandboxResyncPeriodSeconds == 0 {
+		daemonNetConf.SandboxResyncPeriodSeconds = defaultSandboxResyncPeriodSeconds
+	}
+
+	if daemonNetConf.MaxRetryBackoffSeconds < 0 {
+		return nil, fmt.Errorf("invalid max retry backoff: %d", daemonNetConf.MaxRetryBackoffSeconds)
+	}
+
+	if daemonNetConf.MaxRetryBackoffSeconds == 0 {
+		daemonNetConf.MaxRetryBackoffSeconds = defaultMaxRetryBackoffSeconds
+	}
+
+	for errorClass, retryPolicy := range daemonNetConf.RetryPolicies {
+		if err := validateRetryPolicy(errorClass, retryPolicy); err != nil {
+			return nil, err
+		}
+	}
+
 	return daemonNetConf, nil
 }
+
+func validateRetryPolicy(errorClass string, retryPolicy RetryPolicy) error {
+	if _, isKnown := errorClasses[errorClass]; !isKnown {
+		return fmt.Errorf("invalid retry policy: unknown error class %q", errorClass)
+	}
+	if retryPolicy.MaxRetries != nil && *retryPolicy.MaxRetries < -1 {
+		return fmt.Errorf("invalid %s retry policy: max retries %d", errorClass, *retryPolicy.MaxRetries)
+	}
+	if retryPolicy.BaseBackoffMilliseconds < 0 {
+		return fmt.Errorf("invalid %s retry policy: base backoff %d", errorClass, retryPolicy.BaseBackoffMilliseconds)
+	}
+	if retryPolicy.MaxBackoffSeconds < 0 {
+		return fmt.Errorf("invalid %s retry policy: max backoff %d", errorClass, retryPolicy.MaxBackoffSeconds)
+	}
+	return nil
+}
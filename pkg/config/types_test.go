@@ -62,6 +62,69 @@ var _ = Describe("The dynamic network attachment configuration", func() {
 						return multusConfig.CriSocketPath
 					}, Equal(containerdSocketPath)))
 			})
+
+			It("specifies a default liveness window", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) int {
+						return multusConfig.LivenessWindowSeconds
+					}, Equal(defaultLivenessWindowSeconds)))
+			})
+
+			It("specifies a single worker as default", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) int {
+						return multusConfig.Workers
+					}, Equal(defaultWorkers)))
+			})
+
+			It("specifies a default shutdown grace period", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) int {
+						return multusConfig.ShutdownGracePeriodSeconds
+					}, Equal(defaultShutdownGracePeriodSeconds)))
+			})
+
+			It("invokes the CNI plugins through multus by default", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) string {
+						return multusConfig.CNIInvocationMode
+					}, Equal(CNIInvocationMultus)))
+			})
+
+			It("logs text by default", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) string {
+						return multusConfig.LogFormat
+					}, Equal(LogFormatText)))
+			})
+
+			It("specifies default delegate timeouts", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) []int {
+						return []int{multusConfig.DelegateAddTimeoutSeconds, multusConfig.DelegateDelTimeoutSeconds}
+					}, Equal([]int{defaultDelegateAddTimeoutSeconds, defaultDelegateDelTimeoutSeconds})))
+			})
+
+			It("specifies a default max retry backoff", func() {
+				Expect(
+					LoadConfig(configurationFilePath(configurationDir)),
+				).To(
+					WithTransform(func(multusConfig *Multus) int {
+						return multusConfig.MaxRetryBackoffSeconds
+					}, Equal(defaultMaxRetryBackoffSeconds)))
+			})
 		})
 
 		Context("overriding the configuration defaults", func() {
@@ -87,6 +150,193 @@ var _ = Describe("The dynamic network attachment configuration", func() {
 		Expect(err).To(MatchError(nonExistentPathError(aPath)))
 	})
 
+	It("fails when the number of workers is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"workers": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid number of workers: -1"))
+	})
+
+	It("fails when the shutdown grace period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"shutdownGracePeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid shutdown grace period: -1"))
+	})
+
+	It("specifies the default CNI directories when invoking the plugins directly", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"cniInvocationMode": "direct", "cniBinDir": "/usr/libexec/cni"}`), allowAllPermissions),
+		).To(Succeed())
+
+		Expect(
+			LoadConfig(configurationFilePath(configurationDir)),
+		).To(
+			WithTransform(func(multusConfig *Multus) []string {
+				return []string{multusConfig.CNIBinDir, multusConfig.CNIConfDir, multusConfig.CNICacheDir}
+			}, Equal([]string{"/usr/libexec/cni", defaultCNIConfDir, defaultCNICacheDir})))
+	})
+
+	It("fails when the CNI invocation mode is unknown", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"cniInvocationMode": "thin"}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError(`invalid CNI invocation mode: "thin"`))
+	})
+
+	It("fails when the log format is unknown", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"logFormat": "xml"}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError(`invalid log format: "xml"`))
+	})
+
+	It("fails when the drift audit period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"driftAuditPeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid drift audit period: -1"))
+	})
+
+	It("fails when the attachment check period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"attachmentCheckPeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid attachment check period: -1"))
+	})
+
+	It("fails when the garbage collection period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"garbageCollectionPeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid garbage collection period: -1"))
+	})
+
+	DescribeTable("fails when a CNI 1.1 verb is enabled without invoking the plugins directly", func(config string, expectedErr string) {
+		Expect(
+			os.WriteFile(configurationFilePath(configurationDir), []byte(config), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError(expectedErr))
+	},
+		Entry("GC", `{"garbageCollectionPeriodSeconds": 60}`, `the garbage collection requires the "direct" CNI invocation mode`),
+		Entry("STATUS", `{"checkPluginStatus": true}`, `the plugin status check requires the "direct" CNI invocation mode`),
+	)
+
+	It("fails when the resync period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"resyncPeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid resync period: -1"))
+	})
+
+	It("fails when the sandbox resync period is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"sandboxResyncPeriodSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid sandbox resync period: -1"))
+	})
+
+	DescribeTable("fails when a delegate timeout is negative", func(config string, expectedErr string) {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(config), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError(expectedErr))
+	},
+		Entry("ADD", `{"delegateAddTimeoutSeconds": -1}`, "invalid delegate ADD timeout: -1"),
+		Entry("DEL", `{"delegateDelTimeoutSeconds": -1}`, "invalid delegate DEL timeout: -1"),
+	)
+
+	It("fails when the max retry backoff is negative", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"maxRetryBackoffSeconds": -1}`), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError("invalid max retry backoff: -1"))
+	})
+
+	It("loads the retry policies of the error classes", func() {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(`{"retryPolicies": {"Permanent": {"maxRetries": 0}, "NotFound": {"baseBackoffMilliseconds": 500}}}`),
+				allowAllPermissions),
+		).To(Succeed())
+
+		noRetries := 0
+		Expect(
+			LoadConfig(configurationFilePath(configurationDir)),
+		).To(
+			WithTransform(func(multusConfig *Multus) map[string]RetryPolicy {
+				return multusConfig.RetryPolicies
+			}, Equal(map[string]RetryPolicy{
+				"Permanent": {MaxRetries: &noRetries},
+				"NotFound":  {BaseBackoffMilliseconds: 500},
+			})))
+	})
+
+	DescribeTable("fails when a retry policy is invalid", func(retryPolicies string, expectedErr string) {
+		Expect(
+			os.WriteFile(
+				configurationFilePath(configurationDir),
+				[]byte(fmt.Sprintf(`{"retryPolicies": %s}`, retryPolicies)), allowAllPermissions),
+		).To(Succeed())
+
+		_, err := LoadConfig(configurationFilePath(configurationDir))
+		Expect(err).To(MatchError(expectedErr))
+	},
+		Entry("unknown error class", `{"Flaky": {}}`, `invalid retry policy: unknown error class "Flaky"`),
+		Entry("max retries below -1", `{"Transient": {"maxRetries": -2}}`, "invalid Transient retry policy: max retries -2"),
+		Entry("negative base backoff", `{"Transient": {"baseBackoffMilliseconds": -1}}`, "invalid Transient retry policy: base backoff -1"),
+		Entry("negative max backoff", `{"NotFound": {"maxBackoffSeconds": -1}}`, "invalid NotFound retry policy: max backoff -1"),
+	)
+
 	It("fails when the config file does not feature valid json", func() {
 		Expect(
 			os.WriteFile(
@@ -106,8 +356,17 @@ func nonExistentPathError(configDir string) string {
 
 func crioConfig(criSocketPath string, multusSocketPath string) *Multus {
 	return &Multus{
-		CriSocketPath:    criSocketPath,
-		MultusSocketPath: multusSocketPath,
+		CriSocketPath:              criSocketPath,
+		SandboxResyncPeriodSeconds: defaultSandboxResyncPeriodSeconds,
+		MultusSocketPath:           multusSocketPath,
+		CNIInvocationMode:          CNIInvocationMultus,
+		LogFormat:                  LogFormatText,
+		LivenessWindowSeconds:      defaultLivenessWindowSeconds,
+		Workers:                    defaultWorkers,
+		ShutdownGracePeriodSeconds: defaultShutdownGracePeriodSeconds,
+		MaxRetryBackoffSeconds:     defaultMaxRetryBackoffSeconds,
+		DelegateAddTimeoutSeconds:  defaultDelegateAddTimeoutSeconds,
+		DelegateDelTimeoutSeconds:  defaultDelegateDelTimeoutSeconds,
 	}
 }
 
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/netns"
+)
+
+// the kinds of interface drifts reported by the auditor
+const (
+	driftMissing  = "missing"
+	driftOrphaned = "orphaned"
+)
+
+// defaultInterfaceName matches the names multus gives the interfaces not requesting one
+var defaultInterfaceName = regexp.MustCompile(`^net[0-9]+$`)
+
+// InterfaceLister lists the network interfaces of the network namespace at the given path.
+type InterfaceLister func(netnsPath string) ([]net.Interface, error)
+
+// WithDriftAudit periodically compares the interfaces of the pods network namespace with their
+// network-status, reporting the drifts using events and metrics. When repair is set, the missing
+// interfaces - and the orphaned ones, when attributable to a requested network - are plugged anew.
+func WithDriftAudit(period time.Duration, repair bool) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.auditPeriod = period
+		pnc.repairDrift = repair
+	}
+}
+
+func withInterfaceLister(interfaceLister InterfaceLister) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.interfaceLister = interfaceLister
+	}
+}
+
+func defaultInterfaceLister() InterfaceLister {
+	return netns.Interfaces
+}
+
+// requestAudits queues the audit of the pods. The audits are performed by the workers, before the
+// pod is reconciled, thus never concurrently with a reconciliation of the same pod.
+func (pnc *PodNetworksController) requestAudits() {
+	pods, err := pnc.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list the pods to audit")
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		if _, hasNetworkStatus := pod.GetAnnotations()[nadv1.NetworkStatusAnnot]; !hasNetworkStatus {
+			continue
+		}
+		namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
+		klog.V(logging.Debug).InfoS("pod added to the audit", "pod", klog.KObj(pod))
+		pnc.pendingAudits.Store(namespacedName, struct{}{})
+		pnc.workqueue.Add(namespacedName)
+	}
+}
+
+func (pnc *PodNetworksController) isAuditRequested(namespacedName string) bool {
+	_, isRequested := pnc.pendingAudits.LoadAndDelete(namespacedName)
+	return isRequested
+}
+
+// auditPod compares the interfaces of the pod network namespace with its network-status. In repair
+// mode, the drifted interfaces are removed, and the given dynamic network-status returned without
+// them, for the ongoing reconciliation to plug the requested ones anew.
+func (pnc *PodNetworksController) auditPod(
+	ctx context.Context,
+	pod *corev1.Pod,
+	dynamicNetworkStatus []nadv1.NetworkStatus,
+	netnsPath string,
+	podSandboxID string,
+) ([]annotations.AttachmentResult, []nadv1.NetworkStatus, error) {
+	logger := klog.FromContext(ctx)
+	namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
+	isCurrent, err := pnc.isCachedPodCurrent(ctx, pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isCurrent {
+		logger.V(logging.Debug).Info("pod is outdated in the cache: skipping its audit")
+		return nil, dynamicNetworkStatus, nil
+	}
+
+	interfaces, err := pnc.interfaceLister(netnsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the interfaces of pod [%s]: %v", namespacedName, err)
+	}
+	// the status given may have been amended - e.g. the attachments of a previous sandbox cleared - since
+	// the pod was read: it is the one audited, along with the default network interfaces of the pod
+	podNetworkStatus, err := annotations.PodDynamicNetworkStatus(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	networkStatus := append(defaultNetworkStatus(podNetworkStatus), dynamicNetworkStatus...)
+	networkSelectionElements, err := annotations.PodNetworkSelectionElements(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexedDynamicAttachments := annotations.IndexDynamicAttachments(dynamicAttachments)
+
+	var attachmentsToRemove []nadv1.NetworkSelectionElement
+	for _, status := range missingInterfaces(networkStatus, interfaces) {
+		logger.Info("interface is missing", "networkAttachmentDefinition", status.Name, "interface", status.Interface)
+		pnc.metrics.InterfaceDrift(driftMissing)
+		pnc.Eventf(pod, corev1.EventTypeWarning, "InterfaceMissing", missingIfaceEventFormat(pod, status))
+
+		networkNamespace, networkName, _ := separateNamespaceAndName(status.Name)
+		attachment := nadv1.NetworkSelectionElement{
+			Name:             networkName,
+			Namespace:        networkNamespace,
+			InterfaceRequest: status.Interface,
+		}
+		if dynamicAttachment, wasRecorded := indexedDynamicAttachments[annotations.NetworkStatusIndexKey(status)]; wasRecorded {
+			attachment = dynamicAttachment.NetworkSelectionElement
+			attachment.InterfaceRequest = status.Interface
+		}
+		attachmentsToRemove = append(attachmentsToRemove, attachment)
+	}
+
+	for _, iface := range orphanedInterfaces(networkStatus, interfaces, networkSelectionElements) {
+		logger.Info("interface is not listed in the network-status", "interface", iface)
+		pnc.metrics.InterfaceDrift(driftOrphaned)
+		pnc.Eventf(pod, corev1.EventTypeWarning, "InterfaceOrphaned", orphanedIfaceEventFormat(pod, iface))
+
+		// only the orphans requested by the pod can be removed: the network they belong to is known
+		for i := range networkSelectionElements {
+			if networkSelectionElements[i].InterfaceRequest == iface {
+				attachmentsToRemove = append(attachmentsToRemove, networkSelectionElements[i])
+				break
+			}
+		}
+	}
+
+	if !pnc.repairDrift || len(attachmentsToRemove) == 0 {
+		return nil, dynamicNetworkStatus, nil
+	}
+	logger.Info("repairing the drifted interfaces")
+	results, err := pnc.handleDynamicInterfaceRequest(ctx, &DynamicAttachmentRequest{
+		Pod:          pod,
+		Attachments:  attachmentsToRemove,
+		Type:         remove,
+		PodNetNS:     netnsPath,
+		PodSandboxID: podSandboxID,
+	})
+	return results, withoutAttachments(dynamicNetworkStatus, attachmentsToRemove[:len(results)]), err
+}
+
+// isCachedPodCurrent tells whether the network-status - and dynamic attachments - of the cached pod
+// are the current ones: those written by the previous reconciliation may not have reached the pods
+// cache yet.
+func (pnc *PodNetworksController) isCachedPodCurrent(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	currentPod, err := pnc.k8sClientSet.CoreV1().Pods(pod.GetNamespace()).Get(ctx, pod.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get pod [%s]: %v", annotations.NamespacedName(pod.GetNamespace(), pod.GetName()), err)
+	}
+	return currentPod.GetUID() == pod.GetUID() &&
+		currentPod.GetAnnotations()[nadv1.NetworkStatusAnnot] == pod.GetAnnotations()[nadv1.NetworkStatusAnnot] &&
+		currentPod.GetAnnotations()[annotations.DynamicAttachmentsAnnot] == pod.GetAnnotations()[annotations.DynamicAttachmentsAnnot], nil
+}
+
+// withoutAttachments returns the network-status entries not matching any of the attachments, keeping their order.
+func withoutAttachments(networkStatus []nadv1.NetworkStatus, attachments []nadv1.NetworkSelectionElement) []nadv1.NetworkStatus {
+	indexedAttachments := annotations.IndexNetworkSelectionElements(attachments)
+	var remainingStatus []nadv1.NetworkStatus
+	for _, status := range networkStatus {
+		if _, isRemoved := indexedAttachments[annotations.NetworkStatusIndexKey(status)]; !isRemoved {
+			remainingStatus = append(remainingStatus, status)
+		}
+	}
+	return remainingStatus
+}
+
+// missingInterfaces returns the dynamic network-status entries without interface in the pod network namespace.
+func missingInterfaces(networkStatus []nadv1.NetworkStatus, interfaces []net.Interface) []nadv1.NetworkStatus {
+	interfaceNames := map[string]struct{}{}
+	for _, iface := range interfaces {
+		interfaceNames[iface.Name] = struct{}{}
+	}
+
+	var missing []nadv1.NetworkStatus
+	for _, status := range networkStatus {
+		if status.Default {
+			continue
+		}
+		if _, exists := interfaceNames[status.Interface]; !exists {
+			missing = append(missing, status)
+		}
+	}
+	return missing
+}
+
+// defaultNetworkStatus returns the network-status entries of the default network of the pod.
+func defaultNetworkStatus(networkStatus []nadv1.NetworkStatus) []nadv1.NetworkStatus {
+	var defaultStatus []nadv1.NetworkStatus
+	for _, status := range networkStatus {
+		if status.Default {
+			defaultStatus = append(defaultStatus, status)
+		}
+	}
+	return defaultStatus
+}
+
+// orphanedInterfaces returns the interfaces of the pod network namespace not listed in its
+// network-status. Only the interfaces named as the requested attachments - or per the multus
+// naming scheme - are considered: the others, e.g. those created by the pod containers, are none
+// of the controller's business. The interfaces down - e.g. the kernel fallback tunnel devices -
+// are ignored.
+func orphanedInterfaces(
+	networkStatus []nadv1.NetworkStatus,
+	interfaces []net.Interface,
+	networkSelectionElements []nadv1.NetworkSelectionElement,
+) []string {
+	listedInterfaces := map[string]struct{}{}
+	for _, status := range networkStatus {
+		listedInterfaces[status.Interface] = struct{}{}
+	}
+	requestedInterfaces := map[string]struct{}{}
+	for i := range networkSelectionElements {
+		requestedInterfaces[networkSelectionElements[i].InterfaceRequest] = struct{}{}
+	}
+
+	var orphaned []string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if _, isRequested := requestedInterfaces[iface.Name]; !isRequested && !defaultInterfaceName.MatchString(iface.Name) {
+			continue
+		}
+		if _, isListed := listedInterfaces[iface.Name]; !isListed {
+			orphaned = append(orphaned, iface.Name)
+		}
+	}
+	return orphaned
+}
+
+func missingIfaceEventFormat(pod *corev1.Pod, status nadv1.NetworkStatus) string {
+	return fmt.Sprintf(
+		"pod [%s]: interface %s of network %s is missing from the pod network namespace",
+		annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
+		status.Interface,
+		status.Name,
+	)
+}
+
+func orphanedIfaceEventFormat(pod *corev1.Pod, iface string) string {
+	return fmt.Sprintf(
+		"pod [%s]: interface %s is not listed in the pod network-status",
+		annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
+		iface,
+	)
+}
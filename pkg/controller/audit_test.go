@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The interface drift audit", func() {
+	const (
+		auditPeriod = time.Hour
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		k8sClient     *fake.Clientset
+		multusClient  *blockingMultusClient
+		eventRecorder *record.FakeRecorder
+	)
+
+	// the net0 interface of the pod is gone, while net7 was plugged behind the controller's back
+	driftedInterfaces := func(string) ([]net.Interface, error) {
+		return []net.Interface{
+			{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+			{Name: "tunl0"},
+			{Name: "net7", Flags: net.FlagUp},
+		}, nil
+	}
+
+	startController := func(repair bool) {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		multusClient = newBlockingMultusClient(
+			"",
+			fakemultusclient.NewFakeClient(
+				networkConfig(multuscni.CmdAdd, "net0", ""),
+				networkConfig(multuscni.CmdDel, "net0", ""),
+			),
+		)
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				WithDriftAudit(auditPeriod, repair),
+				withInterfaceLister(driftedInterfaces),
+			)).NotTo(BeNil())
+	}
+
+	When("the drifts are only reported", func() {
+		BeforeEach(func() {
+			startController(false)
+		})
+
+		It("reports the interfaces missing from the pod network namespace, and the ones not listed in its network-status", func() {
+			Eventually(<-eventRecorder.Events).Should(Equal(
+				"Warning InterfaceMissing pod [default/tiny-winy-pod]: interface net0 of network default/tiny-net is missing from the pod network namespace",
+			))
+			Eventually(<-eventRecorder.Events).Should(Equal(
+				"Warning InterfaceOrphaned pod [default/tiny-winy-pod]: interface net7 is not listed in the pod network-status",
+			))
+			Consistently(multusClient.invokedDelegates).WithTimeout(time.Second).Should(BeEmpty())
+		})
+	})
+
+	When("the drifts are repaired", func() {
+		BeforeEach(func() {
+			startController(true)
+		})
+
+		It("plugs the missing interfaces anew", func() {
+			Eventually(multusClient.invokedDelegates).Should(Equal([]string{"DEL net0", "ADD net0"}))
+			Eventually(func() ([]nad.NetworkStatus, error) {
+				updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return annotations.PodDynamicNetworkStatus(updatedPod)
+			}).Should(ConsistOf(ifaceStatusForDefaultNamespace(networkName, "net0", "")))
+		})
+	})
+})
+
+var _ = DescribeTable("The orphaned interfaces", func(interfaces []net.Interface, expectedOrphans []string) {
+	networkStatus := []nad.NetworkStatus{
+		{Name: "default/kindnet", Interface: "eth0", Default: true},
+		{Name: "default/tiny-net", Interface: "net1"},
+	}
+	networkSelectionElements := []nad.NetworkSelectionElement{
+		{Name: "tiny-net", Namespace: "default", InterfaceRequest: "net1"},
+		{Name: "data-net", Namespace: "default", InterfaceRequest: "data0"},
+	}
+	Expect(orphanedInterfaces(networkStatus, interfaces, networkSelectionElements)).To(Equal(expectedOrphans))
+},
+	Entry(
+		"are none when all the interfaces are listed in the network-status",
+		[]net.Interface{{Name: "eth0", Flags: net.FlagUp}, {Name: "net1", Flags: net.FlagUp}},
+		nil,
+	),
+	Entry(
+		"include the interfaces named as a requested attachment",
+		[]net.Interface{{Name: "net1", Flags: net.FlagUp}, {Name: "data0", Flags: net.FlagUp}},
+		[]string{"data0"},
+	),
+	Entry(
+		"include the interfaces named per the multus naming scheme",
+		[]net.Interface{{Name: "net1", Flags: net.FlagUp}, {Name: "net7", Flags: net.FlagUp}},
+		[]string{"net7"},
+	),
+	Entry(
+		"ignore the interfaces created by the pod",
+		[]net.Interface{
+			{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+			{Name: "wg0", Flags: net.FlagUp},
+			{Name: "network0", Flags: net.FlagUp},
+		},
+		nil,
+	),
+	Entry(
+		"ignore the interfaces down",
+		[]net.Interface{{Name: "net7"}},
+		nil,
+	),
+)
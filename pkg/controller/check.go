@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+)
+
+// CheckRequestedAnnot is the pod annotation requesting the CNI CHECK of its attachments: setting it - or
+// changing its value, e.g. to the current timestamp - checks them anew.
+const CheckRequestedAnnot = "dynamic-networks-controller.k8s.cni.cncf.io/check-requested"
+
+const reasonCheckFailed = "CheckFailed"
+
+// WithAttachmentCheck periodically issues a CNI CHECK for the attachments listed in the pods
+// network-status, reporting the failed ones using events and metrics. When replug is set, the
+// attachments failing their CHECK are plugged anew. The checks requested using the
+// CheckRequestedAnnot pod annotation are performed regardless of the period.
+func WithAttachmentCheck(period time.Duration, replug bool) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.checkPeriod = period
+		pnc.replugFailedChecks = replug
+	}
+}
+
+// requestChecks queues the CHECK of the pods attachments. As the audits, the checks are performed
+// by the workers, before the pod is reconciled.
+func (pnc *PodNetworksController) requestChecks() {
+	pods, err := pnc.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list the pods to check")
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		if _, hasNetworkStatus := pod.GetAnnotations()[nadv1.NetworkStatusAnnot]; !hasNetworkStatus {
+			continue
+		}
+		pnc.requestCheck(annotations.NamespacedName(pod.GetNamespace(), pod.GetName()))
+	}
+}
+
+func (pnc *PodNetworksController) requestCheck(namespacedName string) {
+	klog.V(logging.Debug).InfoS("pod added to the attachment checks", "pod", namespacedName)
+	pnc.pendingChecks.Store(namespacedName, struct{}{})
+	pnc.workqueue.Add(namespacedName)
+}
+
+func (pnc *PodNetworksController) isCheckRequested(namespacedName string) bool {
+	_, isRequested := pnc.pendingChecks.LoadAndDelete(namespacedName)
+	return isRequested
+}
+
+func didCheckRequestChange(oldPod *corev1.Pod, newPod *corev1.Pod) bool {
+	checkRequest, isRequested := newPod.GetAnnotations()[CheckRequestedAnnot]
+	return isRequested && checkRequest != oldPod.GetAnnotations()[CheckRequestedAnnot]
+}
+
+// checkPod issues a CNI CHECK for the attachments listed in the given dynamic network-status. When
+// re-plugging, the attachments failing their CHECK are removed, and the given dynamic network-status
+// returned without them, for the ongoing reconciliation to plug the requested ones anew.
+func (pnc *PodNetworksController) checkPod(
+	ctx context.Context,
+	pod *corev1.Pod,
+	dynamicNetworkStatus []nadv1.NetworkStatus,
+	netnsPath string,
+	podSandboxID string,
+) ([]annotations.AttachmentResult, []nadv1.NetworkStatus, error) {
+	logger := klog.FromContext(ctx)
+	isCurrent, err := pnc.isCachedPodCurrent(ctx, pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isCurrent {
+		logger.V(logging.Debug).Info("pod is outdated in the cache: skipping its attachments check")
+		return nil, dynamicNetworkStatus, nil
+	}
+
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexedDynamicAttachments := annotations.IndexDynamicAttachments(dynamicAttachments)
+
+	var attachmentsToReplug []nadv1.NetworkSelectionElement
+	for _, status := range dynamicNetworkStatus {
+		if status.Default {
+			continue
+		}
+		attachment, delegateConfig := pnc.checkedAttachment(ctx, status, indexedDynamicAttachments)
+		if delegateConfig == nil {
+			continue
+		}
+
+		attachmentCtx, attachmentLogger := attachmentContext(ctx, multuscni.CmdCheck, attachment)
+		_, err := pnc.invokeDelegate(
+			attachmentCtx,
+			multusapi.CreateDelegateRequest(
+				multuscni.CmdCheck,
+				podSandboxID,
+				netnsPath,
+				attachment.InterfaceRequest,
+				pod.GetNamespace(),
+				pod.GetName(),
+				string(pod.UID),
+				delegateConfig,
+				interfaceAttributes(attachment),
+			))
+		if err != nil {
+			if pnc.isAborted() {
+				return nil, dynamicNetworkStatus, errShuttingDown
+			}
+			attachmentLogger.Info("the attachment failed its CHECK", "err", err)
+			pnc.metrics.AttachmentFailed(multuscni.CmdCheck, attachment.Namespace, attachment.Name, reasonCheckFailed)
+			pnc.Eventf(pod, corev1.EventTypeWarning, "InterfaceCheckFailed", failedCheckIfaceEventFormat(pod, status))
+			attachmentsToReplug = append(attachmentsToReplug, attachment)
+			continue
+		}
+		pnc.metrics.AttachmentSucceeded(multuscni.CmdCheck, attachment.Namespace, attachment.Name)
+	}
+
+	if !pnc.replugFailedChecks || len(attachmentsToReplug) == 0 {
+		return nil, dynamicNetworkStatus, nil
+	}
+	logger.Info("re-plugging the attachments which failed their CHECK")
+	results, err := pnc.handleDynamicInterfaceRequest(ctx, &DynamicAttachmentRequest{
+		Pod:          pod,
+		Attachments:  attachmentsToReplug,
+		Type:         remove,
+		PodNetNS:     netnsPath,
+		PodSandboxID: podSandboxID,
+	})
+	return results, withoutAttachments(dynamicNetworkStatus, attachmentsToReplug[:len(results)]), err
+}
+
+// checkedAttachment returns the attachment of the network-status entry, along with the delegate
+// configuration it was plugged with - or, when not stored, the one of its network-attachment-definition.
+// A nil configuration means the attachment cannot be checked.
+func (pnc *PodNetworksController) checkedAttachment(
+	ctx context.Context,
+	status nadv1.NetworkStatus,
+	indexedDynamicAttachments map[string]annotations.DynamicAttachment,
+) (nadv1.NetworkSelectionElement, []byte) {
+	networkNamespace, networkName, _ := separateNamespaceAndName(status.Name)
+	attachment := nadv1.NetworkSelectionElement{
+		Name:             networkName,
+		Namespace:        networkNamespace,
+		InterfaceRequest: status.Interface,
+	}
+	dynamicAttachment, wasRecorded := indexedDynamicAttachments[annotations.NetworkStatusIndexKey(status)]
+	if wasRecorded {
+		attachment = dynamicAttachment.NetworkSelectionElement
+		attachment.InterfaceRequest = status.Interface
+		if delegateConfig := pnc.storedDelegateConfig(ctx, dynamicAttachment); delegateConfig != nil {
+			return attachment, delegateConfig
+		}
+	}
+
+	netAttachDef, err := pnc.netAttachDef(ctx, networkNamespace, networkName)
+	if err != nil {
+		klog.FromContext(ctx).Info(
+			"cannot check the interface: failed to access its network-attachment-definition",
+			"networkAttachmentDefinition", status.Name,
+			"interface", status.Interface,
+			"err", err,
+		)
+		return attachment, nil
+	}
+	delegateConfig, err := serializeNetAttachDefWithDefaults(netAttachDef)
+	if err != nil {
+		klog.FromContext(ctx).Info("cannot check the interface", "interface", status.Interface, "err", err)
+		return attachment, nil
+	}
+	return attachment, delegateConfig
+}
+
+func failedCheckIfaceEventFormat(pod *corev1.Pod, status nadv1.NetworkStatus) string {
+	return fmt.Sprintf(
+		"pod [%s]: interface %s of network %s failed its CNI CHECK",
+		annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
+		status.Interface,
+		status.Name,
+	)
+}
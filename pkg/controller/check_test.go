@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The attachments CNI CHECK", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		k8sClient     *fake.Clientset
+		multusClient  *blockingMultusClient
+		eventRecorder *record.FakeRecorder
+	)
+
+	startController := func(multusClient multuscni.Client, opts ...Option) {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				opts...,
+			)).NotTo(BeNil())
+	}
+
+	requestCheck := func() {
+		pod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		pod.Annotations[CheckRequestedAnnot] = time.Now().String()
+		_, err = k8sClient.CoreV1().Pods(namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	When("the attachments pass their CHECK", func() {
+		BeforeEach(func() {
+			multusClient = newBlockingMultusClient(
+				"",
+				fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdCheck, "net0", "")),
+			)
+			startController(multusClient, WithAttachmentCheck(100*time.Millisecond, false))
+		})
+
+		It("periodically checks them, without reporting anything", func() {
+			Eventually(multusClient.invokedDelegates).Should(ContainElement("CHECK net0"))
+			Consistently(eventRecorder.Events).WithTimeout(500 * time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	When("the check is requested using the pod annotation", func() {
+		BeforeEach(func() {
+			multusClient = newBlockingMultusClient("", fakemultusclient.NewFakeClient())
+			startController(multusClient)
+			requestCheck()
+		})
+
+		It("reports the attachments failing their CHECK", func() {
+			Eventually(<-eventRecorder.Events).Should(Equal(
+				"Warning InterfaceCheckFailed pod [default/tiny-winy-pod]: interface net0 of network default/tiny-net failed its CNI CHECK",
+			))
+			Consistently(multusClient.invokedDelegates).WithTimeout(500 * time.Millisecond).Should(Equal([]string{"CHECK net0"}))
+		})
+	})
+
+	When("the attachments failing their CHECK are re-plugged", func() {
+		BeforeEach(func() {
+			multusClient = newBlockingMultusClient(
+				"",
+				fakemultusclient.NewFakeClient(
+					networkConfig(multuscni.CmdAdd, "net0", ""),
+					networkConfig(multuscni.CmdDel, "net0", ""),
+				),
+			)
+			startController(multusClient, WithAttachmentCheck(0, true))
+			requestCheck()
+		})
+
+		It("plugs them anew", func() {
+			Eventually(multusClient.invokedDelegates).Should(Equal([]string{"CHECK net0", "DEL net0", "ADD net0"}))
+			Eventually(func() ([]nad.NetworkStatus, error) {
+				updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return annotations.PodDynamicNetworkStatus(updatedPod)
+			}).Should(ConsistOf(ifaceStatusForDefaultNamespace(networkName, "net0", "")))
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+)
+
+// DynamicNetworksReady is the pod condition reporting whether the attachments requested in the
+// pod's networks annotation are plugged. It can be used as a pod readiness gate.
+const DynamicNetworksReady corev1.PodConditionType = "DynamicNetworksReady"
+
+const (
+	// AttachmentsReadyReason is set when the requested attachments are all plugged.
+	AttachmentsReadyReason = "AttachmentsReady"
+	// AttachmentsPendingReason is set while attachments remain to be plugged - or unplugged.
+	AttachmentsPendingReason = "AttachmentsPending"
+	// AttachmentsFailedReason is set once the controller gave up reconciling the attachments.
+	AttachmentsFailedReason = "AttachmentsFailed"
+)
+
+// dynamicNetworksReadyCondition computes the DynamicNetworksReady condition of the pod, out of its
+// network annotations and the outcome of the latest reconciliation of its attachments.
+func dynamicNetworksReadyCondition(pod *corev1.Pod, reconcileErr error, willRetry bool) corev1.PodCondition {
+	condition := corev1.PodCondition{
+		Type:    DynamicNetworksReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  AttachmentsReadyReason,
+		Message: "all the requested attachments are plugged",
+	}
+
+	pending, err := pendingAttachments(pod)
+	if err != nil && reconcileErr == nil {
+		reconcileErr = err
+	}
+
+	switch {
+	case reconcileErr != nil && willRetry:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = AttachmentsPendingReason
+		condition.Message = fmt.Sprintf("retrying to reconcile the attachments %v: %v", pending, reconcileErr)
+	case reconcileErr != nil:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = AttachmentsFailedReason
+		condition.Message = fmt.Sprintf("failed to reconcile the attachments %v: %v", pending, reconcileErr)
+	case len(pending) > 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = AttachmentsPendingReason
+		condition.Message = fmt.Sprintf("pending attachments %v", pending)
+	}
+	return condition
+}
+
+// pendingAttachments returns the attachments requested in the pod's networks annotation missing
+// from its network-status, followed by the ones in its network-status no longer requested.
+func pendingAttachments(pod *corev1.Pod) ([]string, error) {
+	networkSelectionElements, networkStatus, err := getPodNetworks(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, attachment := range newAttachments(networkSelectionElements, annotations.IndexNetworkStatus(networkStatus)) {
+		pending = append(pending, annotations.NetworkSelectionElementIndexKey(attachment))
+	}
+	for _, attachment := range attachmentsToDelete(
+		networkStatus,
+		annotations.IndexNetworkSelectionElements(networkSelectionElements),
+		nil,
+	) {
+		pending = append(pending, annotations.NetworkSelectionElementIndexKey(attachment))
+	}
+	return pending, nil
+}
+
+// hasDynamicNetworks tells whether the DynamicNetworksReady condition is maintained for the pod:
+// either it requests attachments, uses the condition as a readiness gate, or the condition was
+// already set.
+func hasDynamicNetworks(pod *corev1.Pod) bool {
+	if _, hasNetworks := pod.GetAnnotations()[nadv1.NetworkAttachmentAnnot]; hasNetworks {
+		return true
+	}
+	for _, readinessGate := range pod.Spec.ReadinessGates {
+		if readinessGate.ConditionType == DynamicNetworksReady {
+			return true
+		}
+	}
+	return podCondition(pod, DynamicNetworksReady) != nil
+}
+
+// isConditionCurrent tells whether the DynamicNetworksReady condition of the pod reflects its
+// network-status - i.e. all the requested attachments are plugged - or is not maintained.
+func isConditionCurrent(pod *corev1.Pod) bool {
+	if !hasDynamicNetworks(pod) {
+		return true
+	}
+	condition := podCondition(pod, DynamicNetworksReady)
+	return condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == AttachmentsReadyReason
+}
+
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == conditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setPodCondition sets the condition on the pod, keeping its last transition time when the
+// condition status does not change. Returns whether the pod was modified.
+func setPodCondition(pod *corev1.Pod, condition corev1.PodCondition) bool {
+	currentCondition := podCondition(pod, condition.Type)
+	if currentCondition == nil {
+		condition.LastTransitionTime = metav1.Now()
+		pod.Status.Conditions = append(pod.Status.Conditions, condition)
+		return true
+	}
+	if currentCondition.Status == condition.Status &&
+		currentCondition.Reason == condition.Reason &&
+		currentCondition.Message == condition.Message {
+		return false
+	}
+
+	condition.LastTransitionTime = currentCondition.LastTransitionTime
+	if currentCondition.Status != condition.Status {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*currentCondition = condition
+	return true
+}
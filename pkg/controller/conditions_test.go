@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+var _ = Describe("The dynamic networks ready condition", func() {
+	const (
+		namespace = "default"
+		podName   = "tiny-winy-pod"
+		podUID    = "abc-def"
+	)
+
+	DescribeTable("is computed out of the pod networks and the reconciliation outcome",
+		func(networks []string, reconcileErr error, willRetry bool, expectedCondition corev1.PodCondition) {
+			pod := podSpec(podName, namespace, podUID, "tiny-net")
+			pod.Annotations[nad.NetworkAttachmentAnnot] = generateNetworkSelectionAnnotation(namespace, networks...)
+
+			condition := dynamicNetworksReadyCondition(pod, reconcileErr, willRetry)
+			Expect(condition).To(Equal(expectedCondition))
+		},
+		Entry("ready when the requested attachments are plugged", []string{"tiny-net"}, nil, false, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionTrue,
+			Reason:  AttachmentsReadyReason,
+			Message: "all the requested attachments are plugged",
+		}),
+		Entry("pending when attachments remain to be plugged", []string{"tiny-net", "tiny-net-2"}, nil, false, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  AttachmentsPendingReason,
+			Message: "pending attachments [default/tiny-net-2/net1]",
+		}),
+		Entry("pending when attachments remain to be unplugged", []string{}, nil, false, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  AttachmentsPendingReason,
+			Message: "pending attachments [default/tiny-net/net0]",
+		}),
+		Entry(
+			"pending when the reconciliation failed but will be retried",
+			[]string{"tiny-net", "tiny-net-2"},
+			errors.New("boom"),
+			true,
+			corev1.PodCondition{
+				Type:    DynamicNetworksReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  AttachmentsPendingReason,
+				Message: "retrying to reconcile the attachments [default/tiny-net-2/net1]: boom",
+			},
+		),
+		Entry(
+			"failed when the reconciliation failed and will not be retried",
+			[]string{"tiny-net", "tiny-net-2"},
+			errors.New("boom"),
+			false,
+			corev1.PodCondition{
+				Type:    DynamicNetworksReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  AttachmentsFailedReason,
+				Message: "failed to reconcile the attachments [default/tiny-net-2/net1]: boom",
+			},
+		),
+	)
+
+	It("keeps its last transition time while its status does not change", func() {
+		lastTransitionTime := metav1.NewTime(metav1.Now().Add(-time.Hour))
+		pod := podSpec(podName, namespace, podUID, "tiny-net")
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type:               DynamicNetworksReady,
+			Status:             corev1.ConditionFalse,
+			Reason:             AttachmentsPendingReason,
+			Message:            "pending attachments [default/tiny-net-2/net1]",
+			LastTransitionTime: lastTransitionTime,
+		}}
+
+		Expect(setPodCondition(pod, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  AttachmentsPendingReason,
+			Message: "pending attachments [default/tiny-net-2/net1]",
+		})).To(BeFalse())
+
+		Expect(setPodCondition(pod, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  AttachmentsFailedReason,
+			Message: "failed to reconcile the attachments [default/tiny-net-2/net1]: boom",
+		})).To(BeTrue())
+		Expect(podCondition(pod, DynamicNetworksReady).LastTransitionTime).To(Equal(lastTransitionTime))
+
+		Expect(setPodCondition(pod, corev1.PodCondition{
+			Type:    DynamicNetworksReady,
+			Status:  corev1.ConditionTrue,
+			Reason:  AttachmentsReadyReason,
+			Message: "all the requested attachments are plugged",
+		})).To(BeTrue())
+		Expect(podCondition(pod, DynamicNetworksReady).LastTransitionTime).NotTo(Equal(lastTransitionTime))
+	})
+})
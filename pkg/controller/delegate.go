@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/klog/v2"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/tracing"
+)
+
+const (
+	defaultAddTimeout = time.Minute
+	defaultDelTimeout = 30 * time.Second
+)
+
+// WithDelegateTimeouts sets the deadlines of the ADD and DEL delegate invocations; the zero values keep the
+// defaults.
+func WithDelegateTimeouts(addTimeout, delTimeout time.Duration) Option {
+	return func(pnc *PodNetworksController) {
+		if addTimeout > 0 {
+			pnc.addTimeout = addTimeout
+		}
+		if delTimeout > 0 {
+			pnc.delTimeout = delTimeout
+		}
+	}
+}
+
+// invokeDelegate invokes the multus delegate within the deadline of the request CNI command; the GC
+// requests share the DEL deadline, while the CHECK and STATUS requests share the ADD one.
+func (pnc *PodNetworksController) invokeDelegate(ctx context.Context, request *multusapi.Request) (*multusapi.Response, error) {
+	command := request.Env["CNI_COMMAND"]
+	timeout := pnc.addTimeout
+	if command == multuscni.CmdDel || command == multuscni.CmdGC {
+		timeout = pnc.delTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx, span := pnc.tracer.Start(
+		ctx,
+		"InvokeDelegate",
+		trace.WithAttributes(tracing.CNICommandKey.String(command), tracing.CNIIfNameKey.String(request.Env["CNI_IFNAME"])),
+	)
+
+	start := time.Now()
+	response, err := pnc.multusClient.InvokeDelegate(ctx, request)
+	duration := time.Since(start)
+	pnc.metrics.ObserveDelegate(command, duration, err)
+	tracing.End(span, err)
+	klog.FromContext(ctx).V(logging.Debug).Info("invoked the delegate", "command", command, "duration", duration, "failed", err != nil)
+	return response, err
+}
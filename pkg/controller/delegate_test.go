@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The delegate invocations deadlines", func() {
+	const (
+		addTimeout  = 100 * time.Millisecond
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		eventRecorder *record.FakeRecorder
+		multusClient  *blockingMultusClient
+	)
+
+	BeforeEach(func() {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		// the ADD of net1 hangs until its deadline expires
+		multusClient = newBlockingMultusClient(
+			"net1",
+			fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net1", "")),
+		)
+		DeferCleanup(multusClient.release)
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 10
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+		k8sClient := fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				WithDelegateTimeouts(addTimeout, 0),
+				WithRetryPolicy(ErrorClassTransient, RetryPolicy{MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+			)).NotTo(BeNil())
+
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pod, networkName, networkName+"-2"),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails the ADD invocations exceeding their deadline, rolling them back", func() {
+		var event string
+		Eventually(eventRecorder.Events).WithTimeout(10 * addTimeout).Should(Receive(&event))
+		Expect(event).To(Equal(
+			"Warning FailedAddingInterfaceTransient pod [default/tiny-winy-pod]: failed adding interface net1 to network: tiny-net-2",
+		))
+		Eventually(multusClient.invokedDelegates).Should(HaveExactElements("ADD net1", "DEL net1"))
+	})
+})
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/delegates"
+)
+
+// WithDelegateConfigStore keeps the delegate configurations the attachments are plugged with in the given
+// on-host store, so they can be removed after their network-attachment-definition is deleted.
+func WithDelegateConfigStore(store *delegates.Store) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.delegateConfigs = store
+	}
+}
+
+// storeDelegateConfig stores the delegate configuration of an attachment about to be plugged - when
+// the store is enabled - returning the digest recorded along with the attachment.
+func (pnc *PodNetworksController) storeDelegateConfig(delegateConfig []byte) (string, error) {
+	if pnc.delegateConfigs == nil {
+		return delegates.Digest(delegateConfig), nil
+	}
+	return pnc.delegateConfigs.Save(delegateConfig)
+}
+
+// storedDelegateConfig returns the delegate configuration the attachment was plugged with, or nil
+// when it is not stored.
+func (pnc *PodNetworksController) storedDelegateConfig(ctx context.Context, dynamicAttachment annotations.DynamicAttachment) []byte {
+	if pnc.delegateConfigs == nil || dynamicAttachment.DelegateConfigDigest == "" {
+		return nil
+	}
+	delegateConfig, err := pnc.delegateConfigs.Load(dynamicAttachment.DelegateConfigDigest)
+	if err != nil {
+		klog.FromContext(ctx).Info("the delegate configuration of the attachment is not stored", "err", err)
+		return nil
+	}
+	return delegateConfig
+}
+
+// pruneDelegateConfigs removes the stored delegate configurations no longer used by the attachments
+// of the pods on the node.
+func (pnc *PodNetworksController) pruneDelegateConfigs() error {
+	if pnc.delegateConfigs == nil {
+		return nil
+	}
+	pods, err := pnc.podsLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list the pods whose delegate configurations are in use: %v", err)
+	}
+
+	digestsInUse := map[string]bool{}
+	for _, pod := range pods {
+		dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+		if err != nil {
+			// the configurations are kept while the attachments using them cannot be told
+			return err
+		}
+		for i := range dynamicAttachments {
+			digestsInUse[dynamicAttachments[i].DelegateConfigDigest] = true
+		}
+	}
+	return pnc.delegateConfigs.Prune(digestsInUse)
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+)
+
+// ErrorClass classifies the reconciliation errors; each class is retried according to its own RetryPolicy.
+type ErrorClass string
+
+const (
+	// ErrorClassNotFound is the class of the errors caused by a missing resource - e.g. a
+	// network-attachment-definition - which may be created later on.
+	ErrorClassNotFound ErrorClass = "NotFound"
+	// ErrorClassTransient is the class of the errors expected to go away on their own - e.g. a CRI
+	// timeout, or the multus server being unreachable.
+	ErrorClassTransient ErrorClass = "Transient"
+	// ErrorClassPermanent is the class of the errors which retrying will not fix - e.g. an invalid
+	// network-attachment-definition, or a CNI plugin rejecting its configuration.
+	ErrorClassPermanent ErrorClass = "Permanent"
+)
+
+// ErrorClasses lists the error classes.
+var ErrorClasses = []ErrorClass{ErrorClassNotFound, ErrorClassTransient, ErrorClassPermanent}
+
+// ReconcileError is a reconciliation error along with its class.
+type ReconcileError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ReconcileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReconcileError) Unwrap() error {
+	return e.Err
+}
+
+func newNotFoundError(err error) error {
+	return &ReconcileError{Class: ErrorClassNotFound, Err: err}
+}
+
+func newTransientError(err error) error {
+	return &ReconcileError{Class: ErrorClassTransient, Err: err}
+}
+
+func newPermanentError(err error) error {
+	return &ReconcileError{Class: ErrorClassPermanent, Err: err}
+}
+
+// errorClass returns the class of the error; the unclassified errors are deemed transient.
+func errorClass(err error) ErrorClass {
+	var reconcileErr *ReconcileError
+	if errors.As(err, &reconcileErr) {
+		return reconcileErr.Class
+	}
+	return ErrorClassTransient
+}
+
+// lookupError classifies the errors of the informers listers.
+func lookupError(err error) error {
+	if apierrors.IsNotFound(err) {
+		return newNotFoundError(err)
+	}
+	return newTransientError(err)
+}
+
+// permanentCNIErrorCodes are the well-known CNI error codes reporting requests which retrying will not
+// fix: their configuration - or environment - is invalid.
+var permanentCNIErrorCodes = map[uint]struct{}{
+	cnitypes.ErrIncompatibleCNIVersion:      {},
+	cnitypes.ErrUnsupportedField:            {},
+	cnitypes.ErrInvalidEnvironmentVariables: {},
+	cnitypes.ErrDecodingFailure:             {},
+	cnitypes.ErrInvalidNetworkConfig:        {},
+}
+
+// delegateError classifies the errors of the delegate invocations: the CNI errors whose code reports an
+// invalid request are permanent, while the rest - e.g. an IPAM timeout, or failing to reach the multus
+// server - is transient.
+func delegateError(err error) error {
+	if code, isCNIError := cniErrorCode(err); isCNIError {
+		if _, isPermanent := permanentCNIErrorCodes[code]; isPermanent {
+			return newPermanentError(err)
+		}
+	}
+	return newTransientError(err)
+}
+
+// cniErrorCode returns the code of the CNI error returned by the plugin - when invoked directly - or
+// answered by the multus server.
+func cniErrorCode(err error) (uint, bool) {
+	var pluginErr *cnitypes.Error
+	if errors.As(err, &pluginErr) {
+		return pluginErr.Code, true
+	}
+	var responseErr *multuscni.ResponseError
+	if errors.As(err, &responseErr) {
+		var responseCNIErr cnitypes.Error
+		if json.Unmarshal([]byte(responseErr.Body), &responseCNIErr) == nil && responseCNIErr.Code != cnitypes.ErrUnknown {
+			return responseCNIErr.Code, true
+		}
+	}
+	return cnitypes.ErrUnknown, false
+}
+
+// failedEventReason suffixes the reason of the failure events with the error class - e.g.
+// FailedAddingInterfaceNotFound.
+func failedEventReason(reason string, err error) string {
+	return fmt.Sprintf("%s%s", reason, errorClass(err))
+}
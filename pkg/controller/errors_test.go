@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The reconciliation errors classification", func() {
+	DescribeTable("classifies the errors", func(err error, expectedClass ErrorClass) {
+		Expect(errorClass(err)).To(Equal(expectedClass))
+	},
+		Entry("unclassified errors are transient", errors.New("boom"), ErrorClassTransient),
+		Entry(
+			"a missing network-attachment-definition",
+			lookupError(apierrors.NewNotFound(schema.GroupResource{Resource: "network-attachment-definitions"}, "tiny-net")),
+			ErrorClassNotFound,
+		),
+		Entry("a failing lister", lookupError(errors.New("boom")), ErrorClassTransient),
+		Entry(
+			"a CNI plugin failing behind the multus server",
+			delegateError(fmt.Errorf("failed to ADD delegate: %w", &multuscni.ResponseError{StatusCode: http.StatusBadRequest, Body: "IPAM timeout"})),
+			ErrorClassTransient,
+		),
+		Entry(
+			"a CNI plugin rejecting its configuration behind the multus server",
+			delegateError(fmt.Errorf("failed to ADD delegate: %w", &multuscni.ResponseError{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"code": 7, "msg": "invalid master"}`,
+			})),
+			ErrorClassPermanent,
+		),
+		Entry("an unreachable multus server", delegateError(errors.New("failed to send CNI request")), ErrorClassTransient),
+		Entry(
+			"a directly invoked CNI plugin rejecting its configuration",
+			delegateError(fmt.Errorf("failed to ADD delegate: %w", &cnitypes.Error{Code: cnitypes.ErrInvalidNetworkConfig})),
+			ErrorClassPermanent,
+		),
+		Entry(
+			"a directly invoked CNI plugin failing",
+			delegateError(fmt.Errorf("failed to ADD delegate: %w", &cnitypes.Error{Code: cnitypes.ErrInternal})),
+			ErrorClassTransient,
+		),
+		Entry(
+			"a directly invoked CNI plugin asking to try again later",
+			delegateError(fmt.Errorf("failed to ADD delegate: %w", &cnitypes.Error{Code: cnitypes.ErrTryAgainLater})),
+			ErrorClassTransient,
+		),
+		Entry("a wrapped classified error", fmt.Errorf("wrapped: %w", newPermanentError(errors.New("boom"))), ErrorClassPermanent),
+	)
+
+	It("suffixes the failure events reason with the error class", func() {
+		Expect(failedEventReason("FailedAddingInterface", newNotFoundError(errors.New("boom")))).To(Equal("FailedAddingInterfaceNotFound"))
+	})
+})
+
+var _ = Describe("The retry policies", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		k8sClient     *fake.Clientset
+		eventRecorder *record.FakeRecorder
+		multusClient  *rejectingMultusClient
+	)
+
+	BeforeEach(func() {
+		// the pod already requests the attachment, for the startup reconciliation to be the only one
+		// adding it - another one, triggered by an update, would retry it anew
+		pod := updatePodSpec(podSpec(podName, namespace, podUID, networkName), networkName, networkName+"-2")
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		multusClient = &rejectingMultusClient{
+			Client: fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdDel, "net1", "")),
+		}
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 10
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				WithRetryPolicy(ErrorClassPermanent, RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+			)).NotTo(BeNil())
+	})
+
+	It("gives up once the retries of the error class are exhausted", func() {
+		Eventually(<-eventRecorder.Events).Should(Equal(
+			"Warning FailedAddingInterfacePermanent pod [default/tiny-winy-pod]: failed adding interface net1 to network: tiny-net-2",
+		))
+		Eventually(func() (*corev1.PodCondition, error) {
+			updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return podCondition(updatedPod, DynamicNetworksReady), nil
+		}).Should(And(
+			Not(BeNil()),
+			HaveField("Status", corev1.ConditionFalse),
+			HaveField("Reason", AttachmentsFailedReason),
+		))
+		Consistently(multusClient.rejectedAdds).WithTimeout(500 * time.Millisecond).Should(Equal(2))
+	})
+})
+
+// rejectingMultusClient rejects the ADD requests, as a CNI plugin failing to parse its configuration would.
+type rejectingMultusClient struct {
+	multuscni.Client
+
+	lock    sync.Mutex
+	rejects int
+}
+
+func (c *rejectingMultusClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	if req.Env["CNI_COMMAND"] != multuscni.CmdAdd {
+		return c.Client.InvokeDelegate(ctx, req)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rejects++
+	return nil, &multuscni.ResponseError{StatusCode: http.StatusBadRequest, Body: `{"code": 6, "msg": "failed to decode the configuration"}`}
+}
+
+func (c *rejectingMultusClient) rejectedAdds() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.rejects
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+)
+
+const reasonPluginNotReady = "PluginNotReady"
+
+// WithGarbageCollection periodically issues a CNI GC for each network-attachment-definition, listing
+// the attachments of the pods of the given node as the valid ones: the plugins release the resources -
+// e.g. the IPAM allocations - leaked by the attachments which failed to be rolled back.
+func WithGarbageCollection(period time.Duration, nodeName string) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.gcPeriod = period
+		pnc.nodeName = nodeName
+	}
+}
+
+// WithPluginStatusCheck issues a CNI STATUS before adding the attachments of a network; its attachments
+// are not added - and retried as transient errors - until its plugins report they are ready.
+func WithPluginStatusCheck() Option {
+	return func(pnc *PodNetworksController) {
+		pnc.checkPluginStatus = true
+	}
+}
+
+// collectGarbage issues a CNI GC for the network-attachment-definitions. The reconciliations are held
+// meanwhile, and the networks featuring attachments being reconciled are skipped, so that an
+// attachment being plugged is never garbage collected.
+func (pnc *PodNetworksController) collectGarbage() {
+	pnc.gcLock.Lock()
+	defer pnc.gcLock.Unlock()
+
+	ctx := pnc.abortCtx
+	validAttachments, busyNetworks, err := pnc.nodeAttachments(ctx)
+	if err != nil {
+		klog.ErrorS(err, "skipping the garbage collection")
+		return
+	}
+
+	netAttachDefs, err := pnc.netAttachDefLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list the network-attachment-definitions to garbage collect")
+		return
+	}
+	for _, netAttachDef := range netAttachDefs {
+		networkName := annotations.NamespacedName(netAttachDef.GetNamespace(), netAttachDef.GetName())
+		if _, isBusy := busyNetworks[networkName]; isBusy {
+			klog.V(logging.Debug).InfoS(
+				"skipping the garbage collection of the network: attachments are being reconciled",
+				"networkAttachmentDefinition", networkName,
+			)
+			continue
+		}
+		logger := klog.LoggerWithValues(
+			klog.FromContext(ctx),
+			"operation", multuscni.CmdGC,
+			"networkAttachmentDefinition", klog.KObj(netAttachDef),
+		)
+		if err := pnc.collectNetworkGarbage(klog.NewContext(ctx, logger), netAttachDef, validAttachments[networkName]); err != nil {
+			logger.Error(err, "failed to garbage collect the network")
+		}
+	}
+}
+
+func (pnc *PodNetworksController) collectNetworkGarbage(
+	ctx context.Context,
+	netAttachDef *nadv1.NetworkAttachmentDefinition,
+	validAttachments []cnitypes.GCAttachment,
+) error {
+	netAttachDefWithDefaults, err := serializeNetAttachDefWithDefaults(netAttachDef)
+	if err != nil {
+		return err
+	}
+	supported, err := multuscni.SupportsGCAndStatus(netAttachDefWithDefaults)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		klog.FromContext(ctx).V(logging.Debug).Info("skipping the garbage collection of the network: its CNI version predates GC")
+		return nil
+	}
+	request, err := multuscni.NewGCRequest(netAttachDefWithDefaults, validAttachments)
+	if err != nil {
+		return err
+	}
+	if _, err := pnc.invokeDelegate(ctx, request); err != nil {
+		return fmt.Errorf("failed to GC delegate: %w", err)
+	}
+	klog.FromContext(ctx).V(logging.Debug).Info("garbage collected the network")
+	return nil
+}
+
+// nodeAttachments indexes the attachments listed in the network-status of the running pods of the node
+// by network, along with the networks featuring attachments being reconciled. It fails while pods are
+// being set up: their attachments may not be listed yet. The pods are listed from the API, since the
+// informer only features the running ones.
+func (pnc *PodNetworksController) nodeAttachments(
+	ctx context.Context,
+) (map[string][]cnitypes.GCAttachment, map[string]struct{}, error) {
+	podList, err := pnc.k8sClientSet.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", pnc.nodeName).String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the pods: %v", err)
+	}
+
+	validAttachments := map[string][]cnitypes.GCAttachment{}
+	busyNetworks := map[string]struct{}{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.HostNetwork || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
+		if pod.Status.Phase != corev1.PodRunning {
+			return nil, nil, fmt.Errorf("pod [%s] is being set up", namespacedName)
+		}
+
+		networkSelectionElements, networkStatus, err := getPodNetworks(pod)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get the networks of pod [%s]: %v", namespacedName, err)
+		}
+		podSandboxID, err := pnc.podSandboxID(ctx, string(pod.UID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to figure out the PodSandboxID of pod [%s]: %v", namespacedName, err)
+		}
+		for _, status := range networkStatus {
+			validAttachments[status.Name] = append(
+				validAttachments[status.Name],
+				cnitypes.GCAttachment{ContainerID: podSandboxID, IfName: status.Interface},
+			)
+		}
+
+		pending, err := pendingAttachments(pod)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get the pending attachments of pod [%s]: %v", namespacedName, err)
+		}
+		for _, attachment := range pending {
+			networkNamespace, networkName, _ := separateNamespaceAndName(attachment)
+			busyNetworks[annotations.NamespacedName(networkNamespace, networkName)] = struct{}{}
+		}
+		if pnc.numRequeues(namespacedName) > 0 {
+			for i := range networkSelectionElements {
+				busyNetworks[annotations.NamespacedName(networkSelectionElements[i].Namespace, networkSelectionElements[i].Name)] = struct{}{}
+			}
+		}
+	}
+	return validAttachments, busyNetworks, nil
+}
+
+// pluginStatus issues a CNI STATUS for the network configuration, which fails unless its plugins are
+// ready to serve ADD requests. The networks whose CNI version predates STATUS are deemed ready.
+func (pnc *PodNetworksController) pluginStatus(ctx context.Context, netAttachDefWithDefaults []byte) error {
+	if !pnc.checkPluginStatus {
+		return nil
+	}
+	if supported, err := multuscni.SupportsGCAndStatus(netAttachDefWithDefaults); err != nil || !supported {
+		return err
+	}
+	_, err := pnc.invokeDelegate(ctx, multuscni.NewStatusRequest(netAttachDefWithDefaults))
+	return err
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The CNI GC and STATUS support", func() {
+	const (
+		cniVersion       = "1.1.0"
+		namespace        = "default"
+		networkName      = "tiny-net"
+		legacyNetwork    = "legacy-net"
+		podName          = "tiny-winy-pod"
+		podUID           = "abc-def"
+		pendingPodName   = "tiny-winy-pod-2"
+		pendingPodUID    = "ghi-jkl"
+		legacyCNIVersion = "0.4.0"
+	)
+
+	var (
+		k8sClient     *fake.Clientset
+		eventRecorder *record.FakeRecorder
+		multusClient  *recordingMultusClient
+		podSandboxID  string
+	)
+
+	startController := func(pod *corev1.Pod, otherPods []*corev1.Pod, opts ...Option) {
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)),
+			netAttachDef(legacyNetwork, namespace, dummyNetSpec(legacyNetwork, legacyCNIVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		pods := []corev1.Pod{*pod}
+		objects := []k8sruntime.Object{pod}
+		for _, otherPod := range otherPods {
+			pods = append(pods, *otherPod)
+			objects = append(objects, otherPod)
+		}
+		runtime := fakecri.NewFakeRuntime(pods...)
+		podSandboxID, err = runtime.PodSandboxID(context.TODO(), podUID)
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+		k8sClient = fake.NewSimpleClientset(objects...)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				runtime,
+				multusClient,
+				opts...,
+			)).NotTo(BeNil())
+	}
+
+	When("the garbage collection is enabled", func() {
+		BeforeEach(func() {
+			pod := podSpec(podName, namespace, podUID, networkName)
+			pod.Status.Phase = corev1.PodRunning
+			multusClient = &recordingMultusClient{Client: fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdGC, "", ""))}
+			startController(pod, nil, WithGarbageCollection(100*time.Millisecond, "node1"))
+		})
+
+		It("preserves the attachments of the node pods", func() {
+			Eventually(multusClient.validAttachments).WithArguments(networkName).Should(
+				Equal([]cnitypes.GCAttachment{{ContainerID: podSandboxID, IfName: "net0"}}),
+			)
+			Eventually(multusClient.validAttachments).WithArguments(networkName + "-2").Should(BeEmpty())
+		})
+
+		It("skips the networks whose CNI version predates GC", func() {
+			Eventually(multusClient.validAttachments).WithArguments(networkName + "-2").Should(BeEmpty())
+			Expect(multusClient.validAttachments(legacyNetwork)).Error().To(HaveOccurred())
+		})
+	})
+
+	When("the garbage collection is enabled while a pod of the node is being set up", func() {
+		BeforeEach(func() {
+			pod := podSpec(podName, namespace, podUID, networkName)
+			pod.Status.Phase = corev1.PodRunning
+			pendingPod := podSpec(pendingPodName, namespace, pendingPodUID)
+			pendingPod.Status.Phase = corev1.PodPending
+			multusClient = &recordingMultusClient{Client: fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdGC, "", ""))}
+			startController(pod, []*corev1.Pod{pendingPod}, WithGarbageCollection(100*time.Millisecond, "node1"))
+		})
+
+		It("does not garbage collect the networks", func() {
+			Consistently(multusClient.commands).WithTimeout(500 * time.Millisecond).ShouldNot(ContainElement(multuscni.CmdGC))
+		})
+	})
+
+	When("the plugins of a network are not ready", func() {
+		BeforeEach(func() {
+			pod := podSpec(podName, namespace, podUID, networkName)
+			multusClient = &recordingMultusClient{Client: fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net1", ""))}
+			startController(pod, nil, WithPluginStatusCheck(), WithRetryPolicy(ErrorClassTransient, RetryPolicy{MaxRetries: 0}))
+
+			_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+				context.TODO(),
+				updatePodSpec(pod, networkName, networkName+"-2"),
+				metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not add their attachments", func() {
+			Eventually(<-eventRecorder.Events).Should(Equal(
+				"Warning FailedAddingInterfaceTransient pod [default/tiny-winy-pod]: failed adding interface net1 to network: tiny-net-2",
+			))
+			Expect(multusClient.commands()).NotTo(ContainElement(multuscni.CmdAdd))
+			Expect(multusClient.commands()).To(ContainElement(multuscni.CmdStatus))
+		})
+	})
+})
+
+// recordingMultusClient records the delegate requests.
+type recordingMultusClient struct {
+	multuscni.Client
+
+	lock     sync.Mutex
+	requests []*multusapi.Request
+}
+
+func (c *recordingMultusClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	c.lock.Lock()
+	c.requests = append(c.requests, req)
+	c.lock.Unlock()
+	return c.Client.InvokeDelegate(ctx, req)
+}
+
+func (c *recordingMultusClient) commands() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var commands []string
+	for _, req := range c.requests {
+		commands = append(commands, req.Env["CNI_COMMAND"])
+	}
+	return commands
+}
+
+// validAttachments returns the valid attachments of the last GC request of the network.
+func (c *recordingMultusClient) validAttachments(networkName string) ([]cnitypes.GCAttachment, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := len(c.requests) - 1; i >= 0; i-- {
+		if c.requests[i].Env["CNI_COMMAND"] != multuscni.CmdGC {
+			continue
+		}
+		var config struct {
+			Name             string                  `json:"name"`
+			ValidAttachments []cnitypes.GCAttachment `json:"cni.dev/valid-attachments"`
+		}
+		if err := json.Unmarshal(c.requests[i].Config, &config); err != nil {
+			return nil, err
+		}
+		if config.Name == networkName {
+			return config.ValidAttachments, nil
+		}
+	}
+	return nil, fmt.Errorf("network %s was not garbage collected", networkName)
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/journal"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+)
+
+// WithJournal records the CNI operations in the given on-host journal before they are performed,
+// until their outcome is recorded in the pods network-status; the operations interrupted by a
+// crash are replayed on startup.
+func WithJournal(j *journal.Journal) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.journal = j
+	}
+}
+
+func (pnc *PodNetworksController) journalOperation(
+	command string,
+	dynamicAttachmentRequest *DynamicAttachmentRequest,
+	attachment nadv1.NetworkSelectionElement,
+	delegateConfig []byte,
+) error {
+	if pnc.journal == nil {
+		return nil
+	}
+	pod := dynamicAttachmentRequest.Pod
+	return pnc.journal.Record(journal.Operation{
+		Command:                 command,
+		PodNamespace:            pod.GetNamespace(),
+		PodName:                 pod.GetName(),
+		PodUID:                  string(pod.GetUID()),
+		PodSandboxID:            dynamicAttachmentRequest.PodSandboxID,
+		PodNetNS:                dynamicAttachmentRequest.PodNetNS,
+		NetworkSelectionElement: attachment,
+		DelegateConfig:          delegateConfig,
+	})
+}
+
+// completeJournal discards the journaled operations of the pod, once their outcome is recorded -
+// or rolled back.
+func (pnc *PodNetworksController) completeJournal(podUID string) {
+	if pnc.journal == nil {
+		return
+	}
+	if err := pnc.journal.Complete(podUID); err != nil {
+		klog.ErrorS(err, "failed to complete the journal", "podUID", podUID)
+	}
+}
+
+// replayJournal rolls back the attachments whose addition was interrupted before being recorded
+// in the pod network-status, thus preventing the reconciliation from adding them twice. The
+// interrupted removals are completed by the reconciliation of the pods. The operations failing to
+// be rolled back are kept, and rolled back anew when the pod is reconciled.
+func (pnc *PodNetworksController) replayJournal() error {
+	if pnc.journal == nil {
+		return nil
+	}
+	pending, err := pnc.journal.Pending()
+	if err != nil {
+		return err
+	}
+	for podUID, operations := range pending {
+		if err := pnc.rollbackUnrecordedAttachments(podUID, operations); err != nil {
+			klog.ErrorS(err, "keeping the journal of the pod", "podUID", podUID)
+			continue
+		}
+		pnc.completeJournal(podUID)
+	}
+	return nil
+}
+
+// replayPodJournal rolls back the attachments of the pod whose addition is journaled, yet neither
+// recorded nor rolled back: e.g. their rollback failed on startup, or at the end of the previous
+// reconciliation.
+func (pnc *PodNetworksController) replayPodJournal(pod *corev1.Pod) error {
+	if pnc.journal == nil {
+		return nil
+	}
+	podUID := string(pod.GetUID())
+	operations, err := pnc.journal.Operations(podUID)
+	if err != nil || len(operations) == 0 {
+		return err
+	}
+	if err := pnc.rollbackUnrecordedAttachments(podUID, operations); err != nil {
+		return err
+	}
+	pnc.completeJournal(podUID)
+	return nil
+}
+
+// rollbackUnrecordedAttachments removes the journaled attachments missing from the network-status of
+// the pod, as fetched from the API - the informer cache may not feature the latest recorded ones yet.
+func (pnc *PodNetworksController) rollbackUnrecordedAttachments(podUID string, operations []journal.Operation) error {
+	logger := klog.LoggerWithValues(
+		klog.FromContext(pnc.abortCtx),
+		"pod", klog.KRef(operations[0].PodNamespace, operations[0].PodName),
+		"sandboxID", operations[0].PodSandboxID,
+	)
+	pod, err := pnc.k8sClientSet.CoreV1().Pods(operations[0].PodNamespace).Get(
+		pnc.abortCtx,
+		operations[0].PodName,
+		metav1.GetOptions{},
+	)
+	if apierrors.IsNotFound(err) || (err == nil && string(pod.GetUID()) != podUID) {
+		logger.Info("discarding the journal: the pod is gone")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the pod whose attachments to roll back: %v", err)
+	}
+
+	indexedNetworkStatus := annotations.IndexNetworkStatusIgnoringDefaultNetwork(pod)
+	rolledBack := map[string]struct{}{}
+	var rollbackErr error
+	for i := range operations {
+		operation := operations[i]
+		if operation.Command != multuscni.CmdAdd {
+			continue
+		}
+		attachmentKey := annotations.NetworkSelectionElementIndexKey(operation.NetworkSelectionElement)
+		if _, isRecorded := indexedNetworkStatus[attachmentKey]; isRecorded {
+			continue
+		}
+		if _, wasRolledBack := rolledBack[attachmentKey]; wasRolledBack {
+			continue
+		}
+		rolledBack[attachmentKey] = struct{}{}
+
+		attachmentCtx, attachmentLogger := attachmentContext(
+			klog.NewContext(pnc.abortCtx, logger),
+			multuscni.CmdDel,
+			operation.NetworkSelectionElement,
+		)
+		attachmentLogger.Info("rolling back the unrecorded attachment")
+		if _, err := pnc.invokeDelegate(
+			attachmentCtx,
+			multusapi.CreateDelegateRequest(
+				multuscni.CmdDel,
+				operation.PodSandboxID,
+				operation.PodNetNS,
+				operation.NetworkSelectionElement.InterfaceRequest,
+				operation.PodNamespace,
+				operation.PodName,
+				operation.PodUID,
+				operation.DelegateConfig,
+				interfaceAttributes(operation.NetworkSelectionElement),
+			)); err != nil {
+			attachmentLogger.Error(err, "failed to roll back the attachment")
+			rollbackErr = fmt.Errorf("failed to roll back the unrecorded attachment %s: %w", attachmentKey, err)
+		}
+	}
+	return rollbackErr
+}
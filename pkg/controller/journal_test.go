@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/journal"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The operations journal replay", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		k8sClient         *fake.Clientset
+		multusClient      *blockingMultusClient
+		operationsJournal *journal.Journal
+		failedInvocations int
+	)
+
+	journaledAdd := func(networkName string, ifaceName string) journal.Operation {
+		return journal.Operation{
+			Command:      multuscni.CmdAdd,
+			PodNamespace: namespace,
+			PodName:      podName,
+			PodUID:       podUID,
+			PodSandboxID: "sandbox",
+			PodNetNS:     "/var/run/netns/abc",
+			NetworkSelectionElement: nad.NetworkSelectionElement{
+				Name:             networkName,
+				Namespace:        namespace,
+				InterfaceRequest: ifaceName,
+			},
+			DelegateConfig: []byte(dummyNetSpec(networkName, cniVersion)),
+		}
+	}
+
+	BeforeEach(func() {
+		failedInvocations = 0
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		operationsJournal, err = journal.New(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		// the controller crashed after adding the net1 interface, but before recording it
+		Expect(operationsJournal.Record(journaledAdd(networkName, "net0"))).To(Succeed())
+		Expect(operationsJournal.Record(journaledAdd(networkName+"-2", "net1"))).To(Succeed())
+
+		pod := updatePodSpec(podSpec(podName, namespace, podUID, networkName), networkName, networkName+"-2")
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		multusClient = newBlockingMultusClient(
+			"",
+			newFlakyMultusClient(
+				failedInvocations,
+				fakemultusclient.NewFakeClient(
+					networkConfig(multuscni.CmdAdd, "net1", ""),
+					networkConfig(multuscni.CmdDel, "net1", ""),
+				),
+			),
+		)
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				WithJournal(operationsJournal),
+			)).NotTo(BeNil())
+	})
+
+	It("rolls back the unrecorded attachments before reconciling the pod", func() {
+		Eventually(multusClient.invokedDelegates).Should(Equal([]string{"DEL net1", "ADD net1"}))
+		Eventually(func() ([]nad.NetworkStatus, error) {
+			updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return annotations.PodDynamicNetworkStatus(updatedPod)
+		}).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+		))
+	})
+
+	It("completes the journal once the outcome of the operations is recorded", func() {
+		Eventually(operationsJournal.Pending).Should(BeEmpty())
+	})
+
+	When("the rollback of the unrecorded attachments fails", func() {
+		BeforeEach(func() {
+			// both the replay on startup, and the one preceding the first reconcile of the pod, fail
+			failedInvocations = 2
+		})
+
+		It("keeps the journal, and retries the rollback before attaching the network", func() {
+			Eventually(multusClient.invokedDelegates).Should(Equal([]string{"DEL net1", "DEL net1", "DEL net1", "ADD net1"}))
+			Eventually(operationsJournal.Pending).Should(BeEmpty())
+		})
+	})
+})
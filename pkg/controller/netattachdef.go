@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+// SpecChangePolicyAnnot is the network-attachment-definition annotation selecting how
+// the controller reacts to changes of its configuration for the already plugged interfaces.
+const SpecChangePolicyAnnot = "dynamic-networks-controller.k8s.cni.cncf.io/spec-change-policy"
+
+// SpecChangePolicy is the reaction to a network-attachment-definition configuration change
+type SpecChangePolicy string
+
+const (
+	// SpecChangePolicyIgnore keeps the plugged interfaces as they are. It is the default policy.
+	SpecChangePolicyIgnore SpecChangePolicy = "ignore"
+	// SpecChangePolicyWarn emits a warning event on every pod featuring a stale interface.
+	SpecChangePolicyWarn SpecChangePolicy = "warn"
+	// SpecChangePolicyReplug re-plugs the interfaces the controller plugged using the new configuration.
+	SpecChangePolicyReplug SpecChangePolicy = "replug"
+)
+
+const podsByNetAttachDefIndex = "byNetAttachDef"
+
+// podsByNetAttachDef indexes the pods by the network-attachment-definitions referenced in
+// their networks annotation.
+func podsByNetAttachDef(obj interface{}) ([]string, error) {
+	pod, isPod := obj.(*corev1.Pod)
+	if !isPod {
+		return nil, nil
+	}
+	networkSelectionElements, err := annotations.PodNetworkSelectionElements(pod)
+	if err != nil {
+		klog.V(logging.Debug).InfoS("pod not indexed by network-attachment-definition", "pod", klog.KObj(pod), "err", err)
+		return nil, nil
+	}
+
+	var netAttachDefs []string
+	for i := range networkSelectionElements {
+		netAttachDefs = append(
+			netAttachDefs,
+			annotations.NamespacedName(networkSelectionElements[i].Namespace, networkSelectionElements[i].Name),
+		)
+	}
+	return netAttachDefs, nil
+}
+
+func (pnc *PodNetworksController) handleNetAttachDefUpdate(oldObj interface{}, newObj interface{}) {
+	oldNetAttachDef, isNetAttachDef := oldObj.(*nadv1.NetworkAttachmentDefinition)
+	if !isNetAttachDef {
+		return
+	}
+	newNetAttachDef, isNetAttachDef := newObj.(*nadv1.NetworkAttachmentDefinition)
+	if !isNetAttachDef {
+		return
+	}
+	if oldNetAttachDef.Spec.Config == newNetAttachDef.Spec.Config {
+		return
+	}
+
+	netAttachDefName := annotations.NamespacedName(newNetAttachDef.GetNamespace(), newNetAttachDef.GetName())
+	policy := specChangePolicy(newNetAttachDef)
+	klog.InfoS(
+		"the network-attachment-definition configuration changed",
+		"networkAttachmentDefinition", klog.KObj(newNetAttachDef),
+		"policy", policy,
+	)
+	if policy == SpecChangePolicyIgnore {
+		return
+	}
+
+	pods, err := pnc.podsInformer.GetIndexer().ByIndex(podsByNetAttachDefIndex, netAttachDefName)
+	if err != nil {
+		klog.ErrorS(
+			err,
+			"failed to list the pods referencing the network-attachment-definition",
+			"networkAttachmentDefinition", klog.KObj(newNetAttachDef),
+		)
+		return
+	}
+	for _, obj := range pods {
+		pod, isPod := obj.(*corev1.Pod)
+		if !isPod {
+			continue
+		}
+		staleInterfaces := interfacesFromNetwork(pod, netAttachDefName)
+		if len(staleInterfaces) == 0 {
+			continue
+		}
+
+		podName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
+		switch policy {
+		case SpecChangePolicyWarn:
+			pnc.Eventf(pod, corev1.EventTypeWarning, "StaleInterface", staleInterfaceEventFormat(pod, netAttachDefName, staleInterfaces))
+		case SpecChangePolicyReplug:
+			klog.InfoS("pod enqueued to re-plug its interfaces", "pod", klog.KObj(pod), "networkAttachmentDefinition", klog.KObj(newNetAttachDef))
+			pnc.workqueue.Add(podName)
+		}
+	}
+}
+
+// specChangePolicy returns the policy set in the network-attachment-definition annotations,
+// defaulting to ignore.
+func specChangePolicy(netAttachDef *nadv1.NetworkAttachmentDefinition) SpecChangePolicy {
+	policy, wasFound := netAttachDef.GetAnnotations()[SpecChangePolicyAnnot]
+	if !wasFound {
+		return SpecChangePolicyIgnore
+	}
+	switch SpecChangePolicy(policy) {
+	case SpecChangePolicyIgnore, SpecChangePolicyWarn, SpecChangePolicyReplug:
+		return SpecChangePolicy(policy)
+	default:
+		klog.InfoS(
+			"invalid spec change policy; ignoring the configuration changes",
+			"networkAttachmentDefinition", klog.KObj(netAttachDef),
+			"policy", policy,
+		)
+		return SpecChangePolicyIgnore
+	}
+}
+
+// netAttachDefDigest returns the digest of the network-attachment-definition configuration
+func netAttachDefDigest(netAttachDef *nadv1.NetworkAttachmentDefinition) string {
+	digest := sha256.Sum256([]byte(netAttachDef.Spec.Config))
+	return hex.EncodeToString(digest[:])
+}
+
+// isStale reports whether the attachment must be re-plugged since the configuration of its
+// network-attachment-definition changed after it was plugged.
+func (pnc *PodNetworksController) isStale(dynamicAttachment annotations.DynamicAttachment) bool {
+	if dynamicAttachment.NetAttachDefDigest == "" {
+		return false
+	}
+	attachment := dynamicAttachment.NetworkSelectionElement
+	netAttachDef, err := pnc.netAttachDefLister.NetworkAttachmentDefinitions(attachment.Namespace).Get(attachment.Name)
+	if err != nil {
+		return false
+	}
+	return specChangePolicy(netAttachDef) == SpecChangePolicyReplug &&
+		netAttachDefDigest(netAttachDef) != dynamicAttachment.NetAttachDefDigest
+}
+
+func interfacesFromNetwork(pod *corev1.Pod, netAttachDefName string) []string {
+	networkStatus, err := annotations.PodDynamicNetworkStatus(pod)
+	if err != nil {
+		return nil
+	}
+	var interfaces []string
+	for i := range networkStatus {
+		if networkStatus[i].Name == netAttachDefName && !networkStatus[i].Default {
+			interfaces = append(interfaces, networkStatus[i].Interface)
+		}
+	}
+	return interfaces
+}
+
+func staleInterfaceEventFormat(pod *corev1.Pod, netAttachDefName string, interfaces []string) string {
+	return fmt.Sprintf(
+		"pod [%s]: interfaces %v use a stale configuration of network: %s",
+		annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
+		interfaces,
+		netAttachDefName,
+	)
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/delegates"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The network-attachment-definition changes", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		delegateConfigs *delegates.Store
+		eventRecorder   *record.FakeRecorder
+		k8sClient       *fake.Clientset
+		nadClient       nadclient.Interface
+		pod             *corev1.Pod
+		networkToAdd    string
+	)
+
+	updateNetAttachDef := func(netName string, policy SpecChangePolicy) {
+		netAttachDef := netAttachDef(netName, namespace, dummyNetSpec(netName, "1.0.0"))
+		if policy != "" {
+			netAttachDef.Annotations = map[string]string{SpecChangePolicyAnnot: string(policy)}
+		}
+		_, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Update(
+			context.TODO(),
+			&netAttachDef,
+			metav1.UpdateOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		pod = podSpec(podName, namespace, podUID, networkName)
+		networkToAdd = fmt.Sprintf("%s-3", networkName)
+
+		var err error
+		nadClient, err = newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkToAdd, namespace, dummyNetSpec(networkToAdd, cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+
+		delegateConfigs, err = delegates.New(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				fakemultusclient.NewFakeClient(
+					networkConfig(multuscni.CmdAdd, "net2", ""),
+					networkConfig(multuscni.CmdDel, "net2", ""),
+				),
+				WithDelegateConfigStore(delegateConfigs),
+			)).NotTo(BeNil())
+	})
+
+	It("ignores the configuration changes by default", func() {
+		updateNetAttachDef(networkName, "")
+		Consistently(eventRecorder.Events, time.Second).ShouldNot(Receive())
+	})
+
+	It("emits a warning event on the pods featuring interfaces from the network when set to warn", func() {
+		updateNetAttachDef(networkName, SpecChangePolicyWarn)
+		Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+			"Warning StaleInterface pod [%s]: interfaces [net0] use a stale configuration of network: %s",
+			annotations.NamespacedName(namespace, podName),
+			annotations.NamespacedName(namespace, networkName),
+		))))
+	})
+
+	When("an interface was plugged by the controller", func() {
+		BeforeEach(func() {
+			updatedPod := pod.DeepCopy()
+			serializedNetSelectionElements, _ := json.Marshal(append(
+				generateNetworkSelectionElements(namespace, networkName),
+				nad.NetworkSelectionElement{Name: networkToAdd, Namespace: namespace, InterfaceRequest: "net2"},
+			))
+			updatedPod.Annotations[nad.NetworkAttachmentAnnot] = string(serializedNetSelectionElements)
+			_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), updatedPod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+				"Normal AddedInterface pod [%s]: added interface %s to network: %s",
+				annotations.NamespacedName(namespace, podName),
+				"net2",
+				networkToAdd,
+			))))
+			// the event precedes the network-status update: the pod must be up to date for the
+			// network-attachment-definition changes to be reconciled against the plugged interface
+			Eventually(func() ([]string, error) {
+				currentPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return interfacesFromNetwork(currentPod, annotations.NamespacedName(namespace, networkToAdd)), nil
+			}).Should(ConsistOf("net2"))
+		})
+
+		It("re-plugs the interface when set to replug", func() {
+			updateNetAttachDef(networkToAdd, SpecChangePolicyReplug)
+			Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+				"Normal RemovedInterface pod [%s]: removed interface %s from network: %s",
+				annotations.NamespacedName(namespace, podName),
+				"net2",
+				networkToAdd,
+			))))
+			Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+				"Normal AddedInterface pod [%s]: added interface %s to network: %s",
+				annotations.NamespacedName(namespace, podName),
+				"net2",
+				networkToAdd,
+			))))
+		})
+
+		It("records the digest of the delegate configuration, keeping the configuration on the node", func() {
+			var dynamicAttachments []annotations.DynamicAttachment
+			Eventually(func() ([]annotations.DynamicAttachment, error) {
+				currentPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				dynamicAttachments, err = annotations.PodDynamicAttachments(currentPod)
+				return dynamicAttachments, err
+			}).Should(HaveLen(1))
+
+			netAttachDef, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Get(
+				context.TODO(),
+				networkToAdd,
+				metav1.GetOptions{},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			delegateConfig, err := serializeNetAttachDefWithDefaults(netAttachDef)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dynamicAttachments[0].DelegateConfigDigest).To(Equal(delegates.Digest(delegateConfig)))
+			Expect(delegateConfigs.Load(dynamicAttachments[0].DelegateConfigDigest)).To(Equal(delegateConfig))
+		})
+
+		It("removes the interface after the network-attachment-definition is deleted", func() {
+			Expect(nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Delete(
+				context.TODO(),
+				networkToAdd,
+				metav1.DeleteOptions{},
+			)).To(Succeed())
+
+			Eventually(func() ([]string, error) {
+				currentPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return interfacesFromNetwork(currentPod, annotations.NamespacedName(namespace, networkToAdd)), nil
+			}).Should(ConsistOf("net2"))
+			currentPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			currentPod.Annotations[nad.NetworkAttachmentAnnot] = generateNetworkSelectionAnnotation(namespace, networkName)
+			_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), currentPod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+				"Normal RemovedInterface pod [%s]: removed interface %s from network: %s",
+				annotations.NamespacedName(namespace, podName),
+				"net2",
+				networkToAdd,
+			))))
+			Eventually(func() ([]string, error) {
+				updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return interfacesFromNetwork(updatedPod, annotations.NamespacedName(namespace, networkToAdd)), nil
+			}).Should(BeEmpty())
+		})
+	})
+
+	DescribeTable("the spec change policy is read from the network-attachment-definition annotations", func(netAttachDefAnnotations map[string]string, expectedPolicy SpecChangePolicy) {
+		netAttachDef := netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion))
+		netAttachDef.Annotations = netAttachDefAnnotations
+		Expect(specChangePolicy(&netAttachDef)).To(Equal(expectedPolicy))
+	},
+		Entry("defaulting to ignore", nil, SpecChangePolicyIgnore),
+		Entry("when set to warn", map[string]string{SpecChangePolicyAnnot: "warn"}, SpecChangePolicyWarn),
+		Entry("when set to replug", map[string]string{SpecChangePolicyAnnot: "replug"}, SpecChangePolicyReplug),
+		Entry("falling back to ignore on invalid values", map[string]string{SpecChangePolicyAnnot: "reboot"}, SpecChangePolicyIgnore),
+	)
+})
@@ -3,22 +3,33 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	v1coreinformerfactory "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	v1corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
 	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
 	nadinformers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/informers/externalversions"
@@ -27,17 +38,39 @@ import (
 	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
 
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/delegates"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/journal"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/metrics"
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/tracing"
 )
 
 const (
 	AdvertisedName                              = "pod-networks-updates"
-	maxRetries                                  = 2
 	add            DynamicAttachmentRequestType = "add"
 	remove         DynamicAttachmentRequestType = "remove"
 )
 
+const (
+	defaultLivenessWindow      = 5 * time.Minute
+	defaultWorkers             = 1
+	defaultShutdownGracePeriod = 20 * time.Second
+)
+
+// failure reasons reported in the attachment operations metrics
+const (
+	reasonNetAttachDefUnavailable   = "NetAttachDefUnavailable"
+	reasonInvalidNetAttachDef       = "InvalidNetAttachDef"
+	reasonDelegateFailed            = "DelegateFailed"
+	reasonJournalUnavailable        = "JournalUnavailable"
+	reasonDelegateConfigUnavailable = "DelegateConfigUnavailable"
+)
+
+// errStatusNotRecorded is returned when the outcome of the reconciliation of a pod could not be
+// recorded in its status.
+var errStatusNotRecorded = errors.New("error updating pod network status")
+
 type DynamicAttachmentRequestType string
 
 type DynamicAttachmentRequest struct {
@@ -59,7 +92,7 @@ type ContainerRuntime interface {
 func (dar *DynamicAttachmentRequest) String() string {
 	req, err := json.Marshal(dar)
 	if err != nil {
-		klog.Warningf("failed to marshal DynamicAttachmentRequest: %v", err)
+		klog.ErrorS(err, "failed to marshal DynamicAttachmentRequest")
 		return ""
 	}
 	return string(req)
@@ -81,6 +114,64 @@ type PodNetworksController struct {
 	nadClientSet            nadclient.Interface
 	containerRuntime        ContainerRuntime
 	multusClient            multuscni.Client
+	metrics                 *metrics.Metrics
+	livenessWindow          time.Duration
+	lastProgress            atomic.Int64
+	workerHeartbeats        []atomic.Int64
+	workers                 int
+	nodeName                string
+	netAttachDefFinalizer   string
+	delegateConfigs         *delegates.Store
+	releasedFinalizer       string
+	netAttachDefQueue       workqueue.RateLimitingInterface
+	shutdownGracePeriod     time.Duration
+	abortCtx                context.Context
+	abort                   context.CancelFunc
+	addTimeout              time.Duration
+	delTimeout              time.Duration
+	journal                 *journal.Journal
+	auditPeriod             time.Duration
+	repairDrift             bool
+	interfaceLister         InterfaceLister
+	pendingAudits           sync.Map
+	checkPeriod             time.Duration
+	replugFailedChecks      bool
+	pendingChecks           sync.Map
+	gcPeriod                time.Duration
+	gcLock                  sync.RWMutex
+	checkPluginStatus       bool
+	resyncPeriod            time.Duration
+	retryPolicies           map[ErrorClass]RetryPolicy
+	rateLimiters            map[ErrorClass]workqueue.RateLimiter
+	tracer                  trace.Tracer
+}
+
+// Option configures optional behavior of the PodNetworksController
+type Option func(*PodNetworksController)
+
+// WithMetrics instruments the controller using the provided metrics
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.metrics = m
+	}
+}
+
+// WithLivenessWindow sets for how long each of the workers can go without processing any
+// item while there is pending work, before the controller is reported as not alive
+func WithLivenessWindow(window time.Duration) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.livenessWindow = window
+	}
+}
+
+// WithWorkers sets the number of workers processing the pod updates in parallel.
+// Updates for the same pod are always processed sequentially.
+func WithWorkers(workers int) Option {
+	return func(pnc *PodNetworksController) {
+		if workers > 0 {
+			pnc.workers = workers
+		}
+	}
 }
 
 // NewPodNetworksController returns new PodNetworksController instance
@@ -93,6 +184,7 @@ func NewPodNetworksController(
 	nadClientSet nadclient.Interface,
 	containerRuntime ContainerRuntime,
 	multusClient multuscni.Client,
+	opts ...Option,
 ) (*PodNetworksController, error) {
 	podInformer := k8sCoreInformerFactory.Core().V1().Pods().Informer()
 	nadInformer := nadInformers.K8sCniCncfIo().V1().NetworkAttachmentDefinitions().Informer()
@@ -106,62 +198,212 @@ func NewPodNetworksController(
 		netAttachDefLister:      nadInformers.K8sCniCncfIo().V1().NetworkAttachmentDefinitions().Lister(),
 		recorder:                recorder,
 		broadcaster:             broadcaster,
-		workqueue: workqueue.NewRateLimitingQueueWithConfig(
-			workqueue.DefaultControllerRateLimiter(),
-			workqueue.RateLimitingQueueConfig{Name: AdvertisedName},
-		),
-		k8sClientSet:     k8sClientSet,
-		nadClientSet:     nadClientSet,
-		containerRuntime: containerRuntime,
-		multusClient:     multusClient,
+		k8sClientSet:            k8sClientSet,
+		nadClientSet:            nadClientSet,
+		containerRuntime:        containerRuntime,
+		multusClient:            multusClient,
+		livenessWindow:          defaultLivenessWindow,
+		workers:                 defaultWorkers,
+		shutdownGracePeriod:     defaultShutdownGracePeriod,
+		addTimeout:              defaultAddTimeout,
+		delTimeout:              defaultDelTimeout,
+		interfaceLister:         defaultInterfaceLister(),
+		retryPolicies:           defaultRetryPolicies(),
+		tracer:                  defaultTracer(),
+	}
+	podNetworksController.abortCtx, podNetworksController.abort = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(podNetworksController)
 	}
+	podNetworksController.workerHeartbeats = make([]atomic.Int64, podNetworksController.workers)
+	podNetworksController.recordProgress()
+	if podNetworksController.metrics == nil {
+		podNetworksController.metrics = metrics.New()
+	}
+
+	// the failed reconciliations are re-queued per the retry policy of the error class
+	podNetworksController.rateLimiters = newRetryRateLimiters(podNetworksController.retryPolicies)
+	podNetworksController.workqueue = workqueue.NewRateLimitingQueueWithConfig(
+		workqueue.DefaultControllerRateLimiter(),
+		workqueue.RateLimitingQueueConfig{
+			Name:            AdvertisedName,
+			MetricsProvider: podNetworksController.metrics.WorkqueueMetricsProvider(),
+		},
+	)
 
-	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc:    podNetworksController.handlePodAdd,
 		UpdateFunc: podNetworksController.handlePodUpdate,
 	}); err != nil {
 		return nil, fmt.Errorf("error setting the add event handlers: %v", err)
 	}
 
+	if err := podInformer.AddIndexers(cache.Indexers{podsByNetAttachDefIndex: podsByNetAttachDef}); err != nil {
+		return nil, fmt.Errorf("error indexing the pods by network-attachment-definition: %v", err)
+	}
+
+	if err := podInformer.AddIndexers(cache.Indexers{podsByUIDIndex: podsByUID}); err != nil {
+		return nil, fmt.Errorf("error indexing the pods by UID: %v", err)
+	}
+
+	if _, err := nadInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: podNetworksController.handleNetAttachDefUpdate,
+	}); err != nil {
+		return nil, fmt.Errorf("error setting the network-attachment-definition event handlers: %v", err)
+	}
+
+	// the pods whose sandbox changed are reconciled right away, when the runtime notifies it
+	if notifier, isNotifier := containerRuntime.(sandboxChangeNotifier); isNotifier {
+		notifier.OnSandboxChange(podNetworksController.handleSandboxChange)
+	}
+
+	if podNetworksController.netAttachDefFinalizer != "" {
+		if err := podNetworksController.setupNetAttachDefProtection(podInformer, nadInformer); err != nil {
+			return nil, err
+		}
+	}
+
 	return podNetworksController, nil
 }
 
-// Start runs worker thread after performing cache synchronization
+// Start runs the worker threads after performing cache synchronization
 func (pnc *PodNetworksController) Start(stopChan <-chan struct{}) {
-	klog.Infof("starting network controller")
+	klog.InfoS("starting network controller")
 	defer pnc.workqueue.ShutDown()
 
 	if ok := cache.WaitForCacheSync(stopChan, pnc.arePodsSynched, pnc.areNetAttachDefsSynched); !ok {
-		klog.Infof("failed waiting for caches to sync")
+		klog.InfoS("failed waiting for caches to sync")
 		return
 	}
 
 	// ensure that we didn't miss any updates before the cache sync completion
 	if err := pnc.reconcileOnStartup(); err != nil {
-		klog.Infof("failed to reconcile pods on startup: %v", err)
+		klog.ErrorS(err, "failed to reconcile pods on startup")
 		return
 	}
 
-	go wait.Until(pnc.worker, time.Second, stopChan)
+	// the workqueue never hands out the same key to more than one worker at a
+	// time, thus the updates for a given pod are strictly serialized.
+	klog.InfoS("starting workers", "workers", pnc.workers)
+	for i := 0; i < pnc.workers; i++ {
+		go wait.Until(func() { pnc.worker(i) }, time.Second, stopChan)
+	}
+
+	if pnc.auditPeriod > 0 {
+		klog.InfoS("auditing the pods interfaces", "period", pnc.auditPeriod, "repair", pnc.repairDrift)
+		go wait.Until(pnc.requestAudits, pnc.auditPeriod, stopChan)
+	}
+
+	if pnc.checkPeriod > 0 {
+		klog.InfoS("checking the pods attachments", "period", pnc.checkPeriod, "replug", pnc.replugFailedChecks)
+		go wait.Until(pnc.requestChecks, pnc.checkPeriod, stopChan)
+	}
+
+	if pnc.gcPeriod > 0 {
+		klog.InfoS("garbage collecting the networks", "period", pnc.gcPeriod)
+		go wait.Until(pnc.collectGarbage, pnc.gcPeriod, stopChan)
+	}
+
+	if pnc.resyncPeriod > 0 {
+		klog.InfoS("resyncing the pods", "period", pnc.resyncPeriod)
+		go wait.UntilWithContext(wait.ContextForChannel(stopChan), pnc.resync, pnc.resyncPeriod)
+	}
+
+	if pnc.netAttachDefQueue != nil {
+		defer pnc.netAttachDefQueue.ShutDown()
+		klog.InfoS("protecting the network-attachment-definitions in use", "finalizer", pnc.netAttachDefFinalizer)
+		go wait.UntilWithContext(wait.ContextForChannel(stopChan), pnc.netAttachDefProtectionWorker, time.Second)
+	}
+
+	if pnc.releasedFinalizer != "" {
+		klog.InfoS("releasing the network-attachment-definitions no longer protected", "finalizer", pnc.releasedFinalizer)
+		go func() {
+			if err := pnc.releaseNetAttachDefs(wait.ContextForChannel(stopChan)); err != nil {
+				klog.ErrorS(err, "failed to release the network-attachment-definitions")
+			}
+		}()
+	}
 	<-stopChan
-	klog.Infof("shutting down network controller")
+	klog.InfoS("shutting down network controller")
+	pnc.drain()
+
+	if pnc.releasedFinalizer != "" {
+		// released again, as the previous instance of the controller may have protected network-attachment-definitions
+		// since: the instances overlap while rolling out the disabled protection
+		ctx, cancel := context.WithTimeout(context.Background(), finalizersReleaseTimeout)
+		defer cancel()
+		if err := pnc.releaseNetAttachDefs(ctx); err != nil {
+			klog.ErrorS(err, "failed to release the network-attachment-definitions on shutdown")
+		}
+	}
+}
+
+func (pnc *PodNetworksController) worker(worker int) {
+	for pnc.processNextWorkItem(worker) {
+	}
+}
+
+// CachesSynced reports an error until the pods and network-attachment-definitions caches are synchronized
+func (pnc *PodNetworksController) CachesSynced(_ context.Context) error {
+	if !pnc.arePodsSynched() {
+		return fmt.Errorf("the pods cache is not synchronized")
+	}
+	if !pnc.areNetAttachDefsSynched() {
+		return fmt.Errorf("the network-attachment-definitions cache is not synchronized")
+	}
+	return nil
+}
+
+// WorkersAlive reports an error when there is pending work in the queue, but
+// the workers - or any of them - did not process any item within the liveness window
+func (pnc *PodNetworksController) WorkersAlive(_ context.Context) error {
+	pendingItems := pnc.workqueue.Len()
+	if pendingItems == 0 {
+		pnc.recordProgress()
+		return nil
+	}
+
+	lastProgress := time.Unix(0, pnc.lastProgress.Load())
+	if stalledFor := time.Since(lastProgress); stalledFor > pnc.livenessWindow {
+		return fmt.Errorf("%d items pending in the queue, but none was processed in %s", pendingItems, stalledFor.Round(time.Second))
+	}
+	// the idle workers pick up the pending items right away: a stale heartbeat is a worker stuck
+	// processing an item
+	for i := range pnc.workerHeartbeats {
+		lastHeartbeat := time.Unix(0, pnc.workerHeartbeats[i].Load())
+		if stalledFor := time.Since(lastHeartbeat); stalledFor > pnc.livenessWindow {
+			return fmt.Errorf("%d items pending in the queue, but worker %d processed none in %s", pendingItems, i, stalledFor.Round(time.Second))
+		}
+	}
+	return nil
 }
 
-func (pnc *PodNetworksController) worker() {
-	for pnc.processNextWorkItem() {
+func (pnc *PodNetworksController) recordProgress() {
+	now := time.Now().UnixNano()
+	pnc.lastProgress.Store(now)
+	for i := range pnc.workerHeartbeats {
+		pnc.workerHeartbeats[i].Store(now)
 	}
 }
 
+// recordHeartbeat records the given worker picked up - or completed - an item
+func (pnc *PodNetworksController) recordHeartbeat(worker int) {
+	now := time.Now().UnixNano()
+	pnc.lastProgress.Store(now)
+	pnc.workerHeartbeats[worker].Store(now)
+}
+
 func (pnc *PodNetworksController) ignoreHostNetworkedPods(pod *corev1.Pod) bool {
 	// since there is no such "not has" relation in a field selector,
 	// filter out pods that are of no concern to the controller here
 	if pod.Spec.HostNetwork {
 		_, haveNetworkAttachments := pod.GetAnnotations()[nadv1.NetworkAttachmentAnnot]
-		namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
 		if haveNetworkAttachments {
-			klog.Warningf("rejecting to add interfaces for host networked pod: %s", namespacedName)
+			klog.InfoS("rejecting to add interfaces for host networked pod", "pod", klog.KObj(pod))
 			pnc.Eventf(pod, corev1.EventTypeWarning, "InterfaceAddRejected", rejectInterfaceAddEventFormat(pod))
 		} else {
-			klog.V(logging.Debug).Infof("host networked pod [%s] got filtered out", namespacedName)
+			klog.V(logging.Debug).InfoS("host networked pod got filtered out", "pod", klog.KObj(pod))
 		}
 		return true
 	}
@@ -169,6 +411,13 @@ func (pnc *PodNetworksController) ignoreHostNetworkedPods(pod *corev1.Pod) bool
 }
 
 func (pnc *PodNetworksController) reconcileOnStartup() error {
+	if err := pnc.replayJournal(); err != nil {
+		klog.ErrorS(err, "failed to replay the journal")
+	}
+	if err := pnc.pruneDelegateConfigs(); err != nil {
+		klog.ErrorS(err, "failed to prune the delegate configurations")
+	}
+
 	pods, err := pnc.podsLister.List(labels.Everything())
 	if err != nil {
 		return fmt.Errorf("failed to list pods on current node: %v", err)
@@ -178,65 +427,171 @@ func (pnc *PodNetworksController) reconcileOnStartup() error {
 			continue
 		}
 		namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
-		klog.V(logging.Debug).Infof("pod [%s] added to reconcile on startup", namespacedName)
-		pnc.workqueue.Add(&namespacedName)
+		klog.V(logging.Debug).InfoS("pod added to reconcile on startup", "pod", klog.KObj(pod))
+		pnc.workqueue.Add(namespacedName)
 	}
 	return nil
 }
 
-func (pnc *PodNetworksController) processNextWorkItem() bool {
+func (pnc *PodNetworksController) processNextWorkItem(worker int) bool {
 	queueItem, shouldQuit := pnc.workqueue.Get()
 	if shouldQuit {
 		return false
 	}
-	ctx := context.Background()
+	if pnc.workqueue.ShuttingDown() {
+		// the pending items are reconciled on startup; only the in-flight ones are drained
+		pnc.workqueue.Done(queueItem)
+		return false
+	}
+	pnc.recordHeartbeat(worker)
+	defer pnc.recordHeartbeat(worker)
+	// the garbage collection never runs concurrently with the reconciliations
+	pnc.gcLock.RLock()
+	defer pnc.gcLock.RUnlock()
+	// the in-flight CRI and CNI calls are canceled once the shutdown grace period expires
+	ctx := pnc.abortCtx
 
 	defer pnc.workqueue.Done(queueItem)
-	podNamespacedName := queueItem.(*string)
-	klog.Infof("extracted update request for pod [%s] from the queue", *podNamespacedName)
-	podNamespace, podName, err := separateNamespaceAndName(*podNamespacedName)
+	podNamespacedName := queueItem.(string)
+	podNamespace, podName, err := separateNamespaceAndName(podNamespacedName)
 	if err != nil {
-		klog.Errorf("the update key - [%s] - is not in the namespaced name format: %v", *podNamespacedName, err)
+		klog.ErrorS(err, "the update key is not in the namespaced name format", "key", podNamespacedName)
 		return true
 	}
+	// each message logged while reconciling the pod carries its reference - and, once known, its sandbox ID
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), "pod", klog.KRef(podNamespace, podName))
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("extracted update request from the queue")
+	// the CRI calls, delegate invocations and status writes of the reconciliation are traced as child spans
+	ctx, span := pnc.tracer.Start(
+		ctx,
+		"Reconcile",
+		trace.WithAttributes(semconv.K8SNamespaceName(podNamespace), semconv.K8SPodName(podName)),
+	)
 
 	var results []annotations.AttachmentResult
 	var pod *corev1.Pod
 	var netnsPath, podSandboxID string
-	var attachmentsToRollback []nadv1.NetworkSelectionElement
+	var attachmentsToRollback, attachmentsToRestore []nadv1.NetworkSelectionElement
+	isJournalReplayed := true
 	defer func() {
-		err = pnc.handleResult(err, podNamespacedName, pod, results)
-		if err != nil {
-			pnc.handleRollback(netnsPath, podSandboxID, pod, attachmentsToRollback)
+		err = pnc.handleResult(ctx, err, podNamespacedName, pod, results)
+		// the journal is completed once the outcome of the operations is recorded - or rolled back
+		isOutcomeRecorded := !errors.Is(err, errStatusNotRecorded)
+		// the operations performed before the shutdown are recorded as is, and completed on startup
+		if err != nil && !errors.Is(err, errShuttingDown) {
+			restoredResults, rollbackErr := pnc.handleRollback(ctx, netnsPath, podSandboxID, pod, attachmentsToRollback, attachmentsToRestore)
+			if len(restoredResults) > 0 {
+				if updateErr := pnc.updatePodNetworkAnnotations(ctx, pod, append(results, restoredResults...)); updateErr != nil {
+					klog.FromContext(ctx).Error(updateErr, "error recording the restored attachments")
+					rollbackErr = updateErr
+				}
+			}
+			isOutcomeRecorded = rollbackErr == nil
 		}
+		if pod != nil && isJournalReplayed && isOutcomeRecorded {
+			pnc.completeJournal(string(pod.GetUID()))
+		}
+		tracing.End(span, err)
 	}()
 
 	pod, err = pnc.podsLister.Pods(podNamespace).Get(podName)
+	if apierrors.IsNotFound(err) {
+		logger.V(logging.Debug).Info("pod is gone")
+		err = nil
+		return true
+	}
 	if err != nil {
-		klog.Errorf("could not access pod from the informer")
+		logger.Error(err, "could not access pod from the informer")
+		return true
+	}
+
+	if err = pnc.replayPodJournal(pod); err != nil {
+		isJournalReplayed = false
+		err = newTransientError(err)
+		logger.Error(err, "failed to roll back the journaled attachments")
 		return true
 	}
 
 	networkSelectionElements, networkStatus, err := getPodNetworks(pod)
 	if err != nil {
-		klog.Errorf("failed to get pod networks: %v", err)
+		err = newPermanentError(err)
+		logger.Error(err, "failed to get pod networks")
 		return true
 	}
 	indexedNetworkSelectionElements := annotations.IndexNetworkSelectionElements(networkSelectionElements)
 	indexedNetworkStatus := annotations.IndexNetworkStatus(networkStatus)
 
-	netnsPath, err = pnc.containerRuntime.NetworkNamespace(ctx, string(pod.UID))
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
 	if err != nil {
-		klog.Errorf("failed to figure out the pod's network namespace: %v", err)
+		err = newPermanentError(err)
+		logger.Error(err, "failed to get pod dynamic attachments")
 		return true
 	}
+	indexedDynamicAttachments := annotations.IndexDynamicAttachments(dynamicAttachments)
 
-	podSandboxID, err = pnc.containerRuntime.PodSandboxID(ctx, string(pod.UID))
+	netnsPath, err = pnc.networkNamespace(ctx, string(pod.UID))
 	if err != nil {
-		klog.Errorf("failed to figure out the PodSandboxID: %v", err)
+		logger.Error(err, "failed to figure out the pod's network namespace")
 		return true
 	}
 
+	podSandboxID, err = pnc.podSandboxID(ctx, string(pod.UID))
+	if err != nil {
+		logger.Error(err, "failed to figure out the PodSandboxID")
+		return true
+	}
+	logger = klog.LoggerWithValues(logger, "sandboxID", podSandboxID)
+	ctx = klog.NewContext(ctx, logger)
+	span.SetAttributes(tracing.CNIContainerIDKey.String(podSandboxID))
+
+	// the attachments plugged into a previous sandbox of the pod are gone along with it - unless their
+	// interface is found in the current sandbox: the status of the gone ones is cleared, for the requested
+	// ones to be plugged anew into the current sandbox, while the others are recorded with the current one
+	pluggedAttachments, goneAttachments, err := pnc.previousSandboxAttachments(ctx, pod, dynamicAttachments, netnsPath, podSandboxID)
+	if err != nil {
+		logger.Error(err, "error looking up the attachments of the previous sandbox")
+		return true
+	}
+	for i := range pluggedAttachments {
+		results = append(results, *annotations.NewRelocatedAttachmentResult(&pluggedAttachments[i], podSandboxID))
+	}
+	if len(goneAttachments) > 0 {
+		logger.Info("the pod sandbox was recreated: clearing the attachments of the previous one", "attachments", len(goneAttachments))
+		for i := range goneAttachments {
+			results = append(results, *annotations.NewAttachmentResult(&goneAttachments[i], nil))
+			delete(indexedDynamicAttachments, annotations.NetworkSelectionElementIndexKey(goneAttachments[i]))
+		}
+		networkStatus, err = annotations.DeleteDynamicIfaceFromStatus(networkStatus, goneAttachments...)
+		if err != nil {
+			logger.Error(err, "error clearing the attachments of the previous sandbox")
+			return true
+		}
+		indexedNetworkStatus = annotations.IndexNetworkStatus(networkStatus)
+	}
+
+	if pnc.isAuditRequested(podNamespacedName) {
+		var res []annotations.AttachmentResult
+		res, networkStatus, err = pnc.auditPod(ctx, pod, networkStatus, netnsPath, podSandboxID)
+		results = append(results, res...)
+		if err != nil {
+			logger.Error(err, "error auditing the pod interfaces")
+			return true
+		}
+		indexedNetworkStatus = annotations.IndexNetworkStatus(networkStatus)
+	}
+
+	if pnc.isCheckRequested(podNamespacedName) {
+		var res []annotations.AttachmentResult
+		res, networkStatus, err = pnc.checkPod(ctx, pod, networkStatus, netnsPath, podSandboxID)
+		results = append(results, res...)
+		if err != nil {
+			logger.Error(err, "error checking the pod attachments")
+			return true
+		}
+		indexedNetworkStatus = annotations.IndexNetworkStatus(networkStatus)
+	}
+
 	// The order in which the attachments will be added must be maintained.
 	// Having a deterministic order helps for troubleshooting and testing.
 	// It is also probably required by CNI due, example:
@@ -245,7 +600,9 @@ func (pnc *PodNetworksController) processNextWorkItem() bool {
 	// before the macvlan, then it will fail.
 	attachmentsToAdd := newAttachments(networkSelectionElements, indexedNetworkStatus)
 	if len(attachmentsToAdd) > 0 {
-		results, err = pnc.handleDynamicInterfaceRequest(
+		var res []annotations.AttachmentResult
+		res, err = pnc.handleDynamicInterfaceRequest(
+			ctx,
 			&DynamicAttachmentRequest{
 				Pod:          pod,
 				Attachments:  attachmentsToAdd,
@@ -253,10 +610,11 @@ func (pnc *PodNetworksController) processNextWorkItem() bool {
 				PodNetNS:     netnsPath,
 				PodSandboxID: podSandboxID,
 			})
+		results = append(results, res...)
 		if err != nil {
 			// The number of results will always less than len of attachmentsToAdd if err != nil.
-			attachmentsToRollback = attachmentsToAdd[:len(results)+1]
-			klog.Errorf("error adding attachments: %v", err)
+			attachmentsToRollback = attachmentsToAdd[:len(res)+1]
+			logger.Error(err, "error adding attachments")
 			return true
 		} else {
 			attachmentsToRollback = attachmentsToAdd
@@ -266,10 +624,10 @@ func (pnc *PodNetworksController) processNextWorkItem() bool {
 	// The order in which the attachments will be removed doesn't have to be maintained since CNI DEL must be very permissive
 	// in case of an error (e.g.: Interface already deleted by another attachement should not produce any error).
 	// For troubleshooting and testing, having a deterministic behavior is preferred.
-	attachmentsToRemove := attachmentsToDelete(networkStatus, indexedNetworkSelectionElements)
+	attachmentsToRemove := attachmentsToDelete(networkStatus, indexedNetworkSelectionElements, indexedDynamicAttachments)
 	if len(attachmentsToRemove) > 0 {
 		var res []annotations.AttachmentResult
-		res, err = pnc.handleDynamicInterfaceRequest(&DynamicAttachmentRequest{
+		res, err = pnc.handleDynamicInterfaceRequest(ctx, &DynamicAttachmentRequest{
 			Pod:          pod,
 			Attachments:  attachmentsToRemove,
 			Type:         remove,
@@ -278,7 +636,29 @@ func (pnc *PodNetworksController) processNextWorkItem() bool {
 		})
 		results = append(results, res...)
 		if err != nil {
-			klog.Errorf("error removing attachments: %v", err)
+			logger.Error(err, "error removing attachments")
+			return true
+		}
+	}
+
+	// The attachments whose network selection element - or network-attachment-definition configuration,
+	// per its spec change policy - changed since they were plugged are re-plugged.
+	attachmentsToReplug := pnc.staleAttachments(networkSelectionElements, indexedNetworkStatus, indexedDynamicAttachments)
+	if len(attachmentsToReplug) > 0 {
+		var res []annotations.AttachmentResult
+		var failedAttachments []nadv1.NetworkSelectionElement
+		res, failedAttachments, attachmentsToRestore, err = pnc.replugAttachments(
+			ctx,
+			pod,
+			netnsPath,
+			podSandboxID,
+			attachmentsToReplug,
+			recordedAttachments(attachmentsToReplug, indexedDynamicAttachments),
+		)
+		results = append(results, res...)
+		attachmentsToRollback = append(attachmentsToRollback, failedAttachments...)
+		if err != nil {
+			logger.Error(err, "error re-plugging attachments")
 			return true
 		}
 	}
@@ -286,69 +666,252 @@ func (pnc *PodNetworksController) processNextWorkItem() bool {
 	return true
 }
 
+// replugAttachments removes the attachments using the attributes they were plugged with, then adds
+// them using the new ones. When the add fails, the attachment that failed to be re-plugged is returned
+// to be rolled back, along with the previous attachments to restore.
+func (pnc *PodNetworksController) replugAttachments(
+	ctx context.Context,
+	pod *corev1.Pod,
+	netnsPath, podSandboxID string,
+	attachments, previousAttachments []nadv1.NetworkSelectionElement,
+) ([]annotations.AttachmentResult, []nadv1.NetworkSelectionElement, []nadv1.NetworkSelectionElement, error) {
+	results, err := pnc.handleDynamicInterfaceRequest(ctx, &DynamicAttachmentRequest{
+		Pod:          pod,
+		Attachments:  previousAttachments,
+		Type:         remove,
+		PodNetNS:     netnsPath,
+		PodSandboxID: podSandboxID,
+	})
+	if err != nil {
+		return results, nil, nil, fmt.Errorf("error removing the attachments to re-plug: %w", err)
+	}
+
+	addResults, err := pnc.handleDynamicInterfaceRequest(ctx, &DynamicAttachmentRequest{
+		Pod:          pod,
+		Attachments:  attachments,
+		Type:         add,
+		PodNetNS:     netnsPath,
+		PodSandboxID: podSandboxID,
+	})
+	results = append(results, addResults...)
+	if err != nil {
+		return results, attachments[len(addResults) : len(addResults)+1], previousAttachments[len(addResults):], err
+	}
+	return results, nil, nil, nil
+}
+
 func (pnc *PodNetworksController) handleDynamicInterfaceRequest(
+	ctx context.Context,
 	dynamicAttachmentRequest *DynamicAttachmentRequest,
 ) ([]annotations.AttachmentResult, error) {
-	klog.Infof("handleDynamicInterfaceRequest: read from queue: %v", dynamicAttachmentRequest)
+	logger := klog.FromContext(ctx)
+	logger.Info(
+		"handling the dynamic attachment request",
+		"type", dynamicAttachmentRequest.Type,
+		"attachments", len(dynamicAttachmentRequest.Attachments),
+	)
 	switch dynamicAttachmentRequest.Type {
 	case add:
-		return pnc.addNetworks(dynamicAttachmentRequest)
+		return pnc.addNetworks(ctx, dynamicAttachmentRequest)
 	case remove:
-		return pnc.removeNetworks(dynamicAttachmentRequest)
+		return pnc.removeNetworks(ctx, dynamicAttachmentRequest)
 	default:
-		klog.Infof("very weird attachment request: %+v", dynamicAttachmentRequest)
+		logger.Info("very weird attachment request", "type", dynamicAttachmentRequest.Type)
 	}
-	klog.Infof("handleDynamicInterfaceRequest: exited & successfully processed: %v", dynamicAttachmentRequest)
 	return nil, nil
 }
 
 func (pnc *PodNetworksController) handleResult(
+	ctx context.Context,
 	err error,
-	namespacedPodName *string,
+	namespacedPodName string,
 	pod *corev1.Pod,
 	results []annotations.AttachmentResult,
 ) error {
-	namespacedPodNameString := "<nil>"
-	if namespacedPodName != nil {
-		namespacedPodNameString = *namespacedPodName
+	willRetry := err != nil && pnc.shouldRetry(namespacedPodName, err)
+	if pod != nil {
+		if updateError := pnc.updatePodNetworksStatus(ctx, pod, results, err, willRetry); updateError != nil {
+			klog.FromContext(ctx).Error(updateError, "error updating pod network status")
+			return errStatusNotRecorded
+		}
 	}
 
-	if results != nil {
-		updatedStatus, podNetworkStatusUpdateError := annotations.UpdatePodNetworkStatus(pod, results)
-		if podNetworkStatusUpdateError != nil {
-			klog.Errorf(
-				"error computing pod %s updated network status: %v",
-				namespacedPodNameString,
-				podNetworkStatusUpdateError,
-			)
-			return fmt.Errorf("error updating pod network status")
+	if err != nil {
+		if willRetry {
+			pnc.requeue(ctx, namespacedPodName, err)
+			return err
 		}
 
-		if setNetworkStatusError := nadutils.SetNetworkStatus(
-			pnc.k8sClientSet,
-			pod,
-			updatedStatus,
-		); setNetworkStatusError != nil {
-			klog.Errorf("error updating pod %s network status: %v", namespacedPodNameString, setNetworkStatusError)
-			return fmt.Errorf("error updating pod network status")
+		klog.FromContext(ctx).Error(err, "giving up on the request", "errorClass", errorClass(err))
+		pnc.forget(namespacedPodName)
+		return err
+	}
+
+	pnc.forget(namespacedPodName)
+	return nil
+}
+
+// updatePodNetworksStatus persists the attachment results in the pod network annotations, along
+// with the DynamicNetworksReady condition reflecting the outcome of the reconciliation.
+func (pnc *PodNetworksController) updatePodNetworksStatus(
+	ctx context.Context,
+	pod *corev1.Pod,
+	results []annotations.AttachmentResult,
+	reconcileErr error,
+	willRetry bool,
+) error {
+	if !hasDynamicNetworks(pod) {
+		if results == nil {
+			return nil
 		}
+		return pnc.updatePodStatus(ctx, pod, results, nil)
 	}
+	return pnc.updatePodStatus(ctx, pod, results, func(currentPod *corev1.Pod) {
+		setPodCondition(currentPod, dynamicNetworksReadyCondition(currentPod, reconcileErr, willRetry))
+	})
+}
 
+// updatePodNetworkAnnotations persists the pod network-status, and the controller's record
+// of the dynamic attachments, once the attachment results are applied to them.
+func (pnc *PodNetworksController) updatePodNetworkAnnotations(
+	ctx context.Context,
+	pod *corev1.Pod,
+	results []annotations.AttachmentResult,
+) error {
+	return pnc.updatePodStatus(ctx, pod, results, nil)
+}
+
+func podNetworkAnnotations(pod *corev1.Pod, results []annotations.AttachmentResult) (map[string]string, error) {
+	updatedStatus, err := annotations.UpdatePodNetworkStatus(pod, results)
+	if err != nil {
+		return nil, fmt.Errorf("error computing the updated network status: %v", err)
+	}
+	serializedStatus, err := json.Marshal(updatedStatus)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing the updated network status: %v", err)
+	}
+
+	updatedAttachments, err := annotations.UpdatePodDynamicAttachments(pod, results)
 	if err != nil {
-		currentRetries := pnc.workqueue.NumRequeues(namespacedPodName)
-		if currentRetries <= maxRetries {
-			klog.Errorf("re-queued request for: %s. Error: %v", namespacedPodNameString, err)
-			pnc.workqueue.AddRateLimited(namespacedPodName)
+		return nil, fmt.Errorf("error computing the updated dynamic attachments: %v", err)
+	}
+	serializedAttachments, err := json.Marshal(updatedAttachments)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing the updated dynamic attachments: %v", err)
+	}
+
+	return map[string]string{
+		nadv1.NetworkStatusAnnot:            string(serializedStatus),
+		annotations.DynamicAttachmentsAnnot: string(serializedAttachments),
+	}, nil
+}
+
+// updatePodStatus applies the attachment results to the pod network annotations, then updates its
+// conditions - if requested - out of the resulting pod. The changes are written as a merge patch
+// guarded by the resourceVersion of the pod they were computed from: on conflict - another writer,
+// e.g. multus, updated the pod meanwhile - the pod is fetched anew, and the status recomputed out of
+// it. The status is written even once the reconciliations are aborted, so that the performed
+// operations are recorded.
+func (pnc *PodNetworksController) updatePodStatus(
+	ctx context.Context,
+	pod *corev1.Pod,
+	results []annotations.AttachmentResult,
+	updateConditions func(currentPod *corev1.Pod),
+) (err error) {
+	ctx, span := pnc.tracer.Start(context.WithoutCancel(ctx), "UpdatePodStatus")
+	defer func() { tracing.End(span, err) }()
+
+	podsClient := pnc.k8sClientSet.CoreV1().Pods(pod.GetNamespace())
+	currentPod := pod
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if currentPod == nil {
+			refreshedPod, err := podsClient.Get(ctx, pod.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if refreshedPod.GetUID() != pod.GetUID() {
+				return fmt.Errorf("the pod was re-created")
+			}
+			currentPod = refreshedPod
+		}
+
+		patch, err := podStatusPatch(currentPod, results, updateConditions)
+		if err != nil || patch == nil {
 			return err
 		}
+		if _, err := podsClient.Patch(ctx, pod.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+			// the status is recomputed out of the current pod on retry
+			currentPod = nil
+			return err
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("status update failed for pod %s: %v", annotations.NamespacedName(pod.GetNamespace(), pod.GetName()), err)
+	}
+	return nil
+}
 
-		pnc.workqueue.Forget(namespacedPodName)
-		return err
+// podStatusPatch computes the merge patch applying the attachment results and the conditions update
+// to the pod, guarded by its resourceVersion; it is nil when the pod status is already up-to-date.
+func podStatusPatch(
+	pod *corev1.Pod,
+	results []annotations.AttachmentResult,
+	updateConditions func(currentPod *corev1.Pod),
+) ([]byte, error) {
+	updatedPod := pod.DeepCopy()
+	if results != nil {
+		podAnnotations, err := podNetworkAnnotations(pod, results)
+		if err != nil {
+			return nil, err
+		}
+		if updatedPod.Annotations == nil {
+			updatedPod.Annotations = map[string]string{}
+		}
+		for key, value := range podAnnotations {
+			updatedPod.Annotations[key] = value
+		}
+	}
+	if updateConditions != nil {
+		updateConditions(updatedPod)
 	}
 
-	pnc.workqueue.Forget(namespacedPodName)
-	return nil
+	metadata := map[string]interface{}{"resourceVersion": pod.GetResourceVersion()}
+	patch := map[string]interface{}{"metadata": metadata}
+	changedAnnotations := map[string]string{}
+	for key, value := range updatedPod.GetAnnotations() {
+		if currentValue, isSet := pod.GetAnnotations()[key]; !isSet || currentValue != value {
+			changedAnnotations[key] = value
+		}
+	}
+	if len(changedAnnotations) > 0 {
+		metadata["annotations"] = changedAnnotations
+	}
+	if !equality.Semantic.DeepEqual(pod.Status.Conditions, updatedPod.Status.Conditions) {
+		patch["status"] = map[string]interface{}{"conditions": updatedPod.Status.Conditions}
+	}
+	if len(changedAnnotations) == 0 && patch["status"] == nil {
+		return nil, nil
+	}
+
+	serializedPatch, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing the pod status patch: %v", err)
+	}
+	return serializedPatch, nil
+}
+
+// handlePodAdd queues the pods - e.g. which just started running - for which the DynamicNetworksReady
+// condition is maintained. The pods listed when the informer starts are reconciled on startup.
+func (pnc *PodNetworksController) handlePodAdd(obj interface{}, isInInitialList bool) {
+	pod := obj.(*corev1.Pod)
+	if isInInitialList || pnc.ignoreHostNetworkedPods(pod) || !hasDynamicNetworks(pod) {
+		return
+	}
+
+	klog.V(logging.Debug).InfoS("pod added", "pod", klog.KObj(pod))
+	pnc.workqueue.Add(annotations.NamespacedName(pod.GetNamespace(), pod.GetName()))
 }
+
 func (pnc *PodNetworksController) handlePodUpdate(oldObj interface{}, newObj interface{}) {
 	oldPod := oldObj.(*corev1.Pod)
 	newPod := newObj.(*corev1.Pod)
@@ -356,39 +919,79 @@ func (pnc *PodNetworksController) handlePodUpdate(oldObj interface{}, newObj int
 	if pnc.ignoreHostNetworkedPods(newPod) {
 		return
 	}
+
+	namespacedName := annotations.NamespacedName(oldPod.GetNamespace(), oldPod.GetName())
+	if didCheckRequestChange(oldPod, newPod) {
+		pnc.requestCheck(namespacedName)
+		return
+	}
 	if !didNetworkSelectionElementsChange(oldPod, newPod) {
 		return
 	}
 
-	namespacedName := annotations.NamespacedName(oldPod.GetNamespace(), oldPod.GetName())
-	klog.V(logging.Debug).Infof("pod [%s] updated", namespacedName)
+	klog.V(logging.Debug).InfoS("pod updated", "pod", klog.KObj(newPod))
 
-	pnc.workqueue.Add(&namespacedName)
+	pnc.workqueue.Add(namespacedName)
 }
 
-func (pnc *PodNetworksController) addNetworks(dynamicAttachmentRequest *DynamicAttachmentRequest) ([]annotations.AttachmentResult, error) {
+func (pnc *PodNetworksController) addNetworks(
+	ctx context.Context,
+	dynamicAttachmentRequest *DynamicAttachmentRequest,
+) ([]annotations.AttachmentResult, error) {
 	pod := dynamicAttachmentRequest.Pod
 
 	var attachmentResults []annotations.AttachmentResult
 	for i := range dynamicAttachmentRequest.Attachments {
+		if pnc.isAborted() {
+			return attachmentResults, errShuttingDown
+		}
 		netToAdd := dynamicAttachmentRequest.Attachments[i]
-		klog.Infof("network to add: %v", netToAdd)
-		failedAddingEvent := func() {
-			pnc.Eventf(pod, corev1.EventTypeWarning, "FailedAddingInterface", failedAddingIfaceEventFormat(pod, &netToAdd))
+		attachmentCtx, logger := attachmentContext(ctx, multuscni.CmdAdd, netToAdd)
+		logger.Info("adding the attachment")
+		failedAddingEvent := func(err error, reason string) {
+			pnc.Eventf(
+				pod,
+				corev1.EventTypeWarning,
+				failedEventReason("FailedAddingInterface", err),
+				failedAddingIfaceEventFormat(pod, &netToAdd),
+			)
+			pnc.metrics.AttachmentFailed(multuscni.CmdAdd, netToAdd.Namespace, netToAdd.Name, reason)
 		}
 
-		netAttachDef, err := pnc.netAttachDefLister.NetworkAttachmentDefinitions(netToAdd.Namespace).Get(netToAdd.Name)
+		netAttachDef, err := pnc.netAttachDef(attachmentCtx, netToAdd.Namespace, netToAdd.Name)
 		if err != nil {
-			failedAddingEvent()
-			klog.Errorf("failed to access the networkattachmentdefinition %s/%s: %v", netToAdd.Namespace, netToAdd.Name, err)
+			err = lookupError(err)
+			failedAddingEvent(err, reasonNetAttachDefUnavailable)
+			logger.Error(err, "failed to access the network-attachment-definition")
 			return attachmentResults, err
 		}
 		netAttachDefWithDefaults, err := serializeNetAttachDefWithDefaults(netAttachDef)
 		if err != nil {
-			failedAddingEvent()
+			err = newPermanentError(err)
+			failedAddingEvent(err, reasonInvalidNetAttachDef)
 			return attachmentResults, err
 		}
-		response, err := pnc.multusClient.InvokeDelegate(
+		if err := pnc.pluginStatus(attachmentCtx, netAttachDefWithDefaults); err != nil {
+			if pnc.isAborted() {
+				return attachmentResults, errShuttingDown
+			}
+			err = newTransientError(fmt.Errorf("the plugins of network %s/%s are not ready: %w", netToAdd.Namespace, netToAdd.Name, err))
+			failedAddingEvent(err, reasonPluginNotReady)
+			return attachmentResults, err
+		}
+		if err := pnc.journalOperation(multuscni.CmdAdd, dynamicAttachmentRequest, netToAdd, netAttachDefWithDefaults); err != nil {
+			err = newTransientError(fmt.Errorf("failed to journal the ADD operation: %v", err))
+			failedAddingEvent(err, reasonJournalUnavailable)
+			return attachmentResults, err
+		}
+		delegateConfigDigest, err := pnc.storeDelegateConfig(netAttachDefWithDefaults)
+		if err != nil {
+			err = newTransientError(fmt.Errorf("failed to store the delegate configuration: %v", err))
+			failedAddingEvent(err, reasonDelegateConfigUnavailable)
+			return attachmentResults, err
+		}
+		response, err := pnc.invokeDelegate(
+			attachmentCtx,
 			multusapi.CreateDelegateRequest(
 				multuscni.CmdAdd,
 				dynamicAttachmentRequest.PodSandboxID,
@@ -401,51 +1004,91 @@ func (pnc *PodNetworksController) addNetworks(dynamicAttachmentRequest *DynamicA
 				interfaceAttributes(netToAdd),
 			))
 
+		if err != nil && pnc.isAborted() {
+			logger.Info("the delegate invocation was canceled by the controller shutdown", "err", err)
+			return attachmentResults, errShuttingDown
+		}
 		if err != nil {
-			failedAddingEvent()
-			return attachmentResults, fmt.Errorf("failed to ADD delegate: %v", err)
+			err = delegateError(fmt.Errorf("failed to ADD delegate: %w", err))
+			failedAddingEvent(err, reasonDelegateFailed)
+			return attachmentResults, err
 		}
-		klog.Infof("response: %v", *response.Result)
 
-		attachmentResults = append(attachmentResults, *annotations.NewAttachmentResult(&netToAdd, response))
-		pnc.Eventf(pod, corev1.EventTypeNormal, "AddedInterface", addIfaceEventFormat(pod, &netToAdd))
-		klog.Infof(
-			"added interface %s to pod %s",
-			annotations.NetworkSelectionElementIndexKey(netToAdd),
-			annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
+		attachmentResults = append(
+			attachmentResults,
+			*annotations.NewAttachmentResult(&netToAdd, response).
+				WithNetAttachDefDigest(netAttachDefDigest(netAttachDef)).
+				WithDelegateConfigDigest(delegateConfigDigest).
+				WithSandboxID(dynamicAttachmentRequest.PodSandboxID),
 		)
+		pnc.metrics.AttachmentSucceeded(multuscni.CmdAdd, netToAdd.Namespace, netToAdd.Name)
+		pnc.Eventf(pod, corev1.EventTypeNormal, "AddedInterface", addIfaceEventFormat(pod, &netToAdd))
+		logger.Info("added the attachment", "result", response.Result)
 	}
 
 	return attachmentResults, nil
 }
 
 func (pnc *PodNetworksController) removeNetworks(
+	ctx context.Context,
 	dynamicAttachmentRequest *DynamicAttachmentRequest,
 ) ([]annotations.AttachmentResult, error) {
 	pod := dynamicAttachmentRequest.Pod
 
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+	if err != nil {
+		return nil, newPermanentError(err)
+	}
+	indexedDynamicAttachments := annotations.IndexDynamicAttachments(dynamicAttachments)
+
 	var attachmentResults []annotations.AttachmentResult
 	for i := range dynamicAttachmentRequest.Attachments {
+		if pnc.isAborted() {
+			return attachmentResults, errShuttingDown
+		}
 		netToRemove := dynamicAttachmentRequest.Attachments[i]
-		klog.Infof("network to remove: %v", dynamicAttachmentRequest.Attachments[i])
-
-		failedRemovingEvent := func() {
-			pnc.Eventf(pod, corev1.EventTypeWarning, "FailedRemovingInterface", failedRemovingIfaceEventFormat(pod, &netToRemove))
+		attachmentCtx, logger := attachmentContext(ctx, multuscni.CmdDel, netToRemove)
+		logger.Info("removing the attachment")
+
+		failedRemovingEvent := func(err error, reason string) {
+			pnc.Eventf(
+				pod,
+				corev1.EventTypeWarning,
+				failedEventReason("FailedRemovingInterface", err),
+				failedRemovingIfaceEventFormat(pod, &netToRemove),
+			)
+			pnc.metrics.AttachmentFailed(multuscni.CmdDel, netToRemove.Namespace, netToRemove.Name, reason)
 		}
 
-		netAttachDef, err := pnc.netAttachDefLister.NetworkAttachmentDefinitions(netToRemove.Namespace).Get(netToRemove.Name)
-		if err != nil {
-			failedRemovingEvent()
-			klog.Errorf("failed to access the network-attachment-definition %s/%s: %v", netToRemove.Namespace, netToRemove.Name, err)
-			return attachmentResults, err
+		// the attachment is removed using the delegate configuration it was plugged with, when stored;
+		// this allows removing it even after its network-attachment-definition was deleted.
+		netAttachDefWithDefaults := pnc.storedDelegateConfig(
+			attachmentCtx,
+			indexedDynamicAttachments[annotations.NetworkSelectionElementIndexKey(netToRemove)],
+		)
+		if len(netAttachDefWithDefaults) == 0 {
+			netAttachDef, err := pnc.netAttachDef(attachmentCtx, netToRemove.Namespace, netToRemove.Name)
+			if err != nil {
+				err = lookupError(err)
+				failedRemovingEvent(err, reasonNetAttachDefUnavailable)
+				logger.Error(err, "failed to access the network-attachment-definition")
+				return attachmentResults, err
+			}
+
+			netAttachDefWithDefaults, err = serializeNetAttachDefWithDefaults(netAttachDef)
+			if err != nil {
+				err = newPermanentError(err)
+				failedRemovingEvent(err, reasonInvalidNetAttachDef)
+				return attachmentResults, err
+			}
 		}
-
-		netAttachDefWithDefaults, err := serializeNetAttachDefWithDefaults(netAttachDef)
-		if err != nil {
-			failedRemovingEvent()
+		if err := pnc.journalOperation(multuscni.CmdDel, dynamicAttachmentRequest, netToRemove, netAttachDefWithDefaults); err != nil {
+			err = newTransientError(fmt.Errorf("failed to journal the DEL operation: %v", err))
+			failedRemovingEvent(err, reasonJournalUnavailable)
 			return attachmentResults, err
 		}
-		_, err = pnc.multusClient.InvokeDelegate(
+		_, err = pnc.invokeDelegate(
+			attachmentCtx,
 			multusapi.CreateDelegateRequest(
 				multuscni.CmdDel,
 				dynamicAttachmentRequest.PodSandboxID,
@@ -457,23 +1100,59 @@ func (pnc *PodNetworksController) removeNetworks(
 				netAttachDefWithDefaults,
 				interfaceAttributes(netToRemove),
 			))
+		if err != nil && pnc.isAborted() {
+			logger.Info("the delegate invocation was canceled by the controller shutdown", "err", err)
+			return attachmentResults, errShuttingDown
+		}
 		if err != nil {
-			failedRemovingEvent()
-			return attachmentResults, fmt.Errorf("failed to remove delegate: %v", err)
+			err = delegateError(fmt.Errorf("failed to remove delegate: %w", err))
+			failedRemovingEvent(err, reasonDelegateFailed)
+			return attachmentResults, err
 		}
 
 		attachmentResults = append(attachmentResults, *annotations.NewAttachmentResult(&netToRemove, nil))
+		pnc.metrics.AttachmentSucceeded(multuscni.CmdDel, netToRemove.Namespace, netToRemove.Name)
 		pnc.Eventf(pod, corev1.EventTypeNormal, "RemovedInterface", removeIfaceEventFormat(pod, &netToRemove))
-		klog.Infof(
-			"removed interface %s from pod %s",
-			annotations.NetworkSelectionElementIndexKey(netToRemove),
-			annotations.NamespacedName(pod.GetNamespace(), pod.GetName()),
-		)
+		logger.Info("removed the attachment")
 	}
 
 	return attachmentResults, nil
 }
 
+// attachmentContext returns the context - and its logger - of the CNI operation performed on the
+// attachment: the messages logged meanwhile carry the operation, network-attachment-definition and interface.
+func attachmentContext(
+	ctx context.Context,
+	operation string,
+	attachment nadv1.NetworkSelectionElement,
+) (context.Context, klog.Logger) {
+	logger := klog.LoggerWithValues(
+		klog.FromContext(ctx),
+		"operation", operation,
+		"networkAttachmentDefinition", klog.KRef(attachment.Namespace, attachment.Name),
+		"interface", attachment.InterfaceRequest,
+	)
+	return klog.NewContext(ctx, logger), logger
+}
+
+func (pnc *PodNetworksController) networkNamespace(ctx context.Context, podUID string) (string, error) {
+	ctx, span := pnc.tracer.Start(ctx, "NetworkNamespace", trace.WithAttributes(semconv.K8SPodUID(podUID)))
+	start := time.Now()
+	netnsPath, err := pnc.containerRuntime.NetworkNamespace(ctx, podUID)
+	pnc.metrics.ObserveCRIRequest("NetworkNamespace", time.Since(start), err)
+	tracing.End(span, err)
+	return netnsPath, err
+}
+
+func (pnc *PodNetworksController) podSandboxID(ctx context.Context, podUID string) (string, error) {
+	ctx, span := pnc.tracer.Start(ctx, "PodSandboxID", trace.WithAttributes(semconv.K8SPodUID(podUID)))
+	start := time.Now()
+	podSandboxID, err := pnc.containerRuntime.PodSandboxID(ctx, podUID)
+	pnc.metrics.ObserveCRIRequest("PodSandboxID", time.Since(start), err)
+	tracing.End(span, err)
+	return podSandboxID, err
+}
+
 // Eventf puts event into kubernetes events
 func (pnc *PodNetworksController) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
 	if pnc != nil && pnc.recorder != nil {
@@ -481,13 +1160,20 @@ func (pnc *PodNetworksController) Eventf(object runtime.Object, eventtype, reaso
 	}
 }
 
+// handleRollback removes the attachments to roll back, then re-adds the attachments to
+// restore using their previous attributes. It returns the results of the restored attachments,
+// along with the errors of the operations which failed.
 func (pnc *PodNetworksController) handleRollback(
+	ctx context.Context,
 	netnsPath, podSandboxID string,
 	pod *corev1.Pod,
 	attachmentsToRollback []nadv1.NetworkSelectionElement,
-) {
+	attachmentsToRestore []nadv1.NetworkSelectionElement,
+) ([]annotations.AttachmentResult, error) {
+	var deleteAttachmentsError error
 	if len(attachmentsToRollback) > 0 {
-		err, deleteAttachmentsError := pnc.handleDynamicInterfaceRequest(
+		_, deleteAttachmentsError = pnc.handleDynamicInterfaceRequest(
+			ctx,
 			&DynamicAttachmentRequest{
 				Pod:          pod,
 				Attachments:  attachmentsToRollback,
@@ -496,9 +1182,26 @@ func (pnc *PodNetworksController) handleRollback(
 				PodSandboxID: podSandboxID,
 			})
 		if deleteAttachmentsError != nil {
-			klog.Errorf("error rollback attachments: %v after handle attachments add/remove", err)
+			klog.FromContext(ctx).Error(deleteAttachmentsError, "error rolling back the attachments")
 		}
 	}
+
+	if len(attachmentsToRestore) == 0 {
+		return nil, deleteAttachmentsError
+	}
+	restoredAttachments, restoreAttachmentsError := pnc.handleDynamicInterfaceRequest(
+		ctx,
+		&DynamicAttachmentRequest{
+			Pod:          pod,
+			Attachments:  attachmentsToRestore,
+			Type:         add,
+			PodNetNS:     netnsPath,
+			PodSandboxID: podSandboxID,
+		})
+	if restoreAttachmentsError != nil {
+		klog.FromContext(ctx).Error(restoreAttachmentsError, "error restoring the previous attachments")
+	}
+	return restoredAttachments, errors.Join(deleteAttachmentsError, restoreAttachmentsError)
 }
 
 func addIfaceEventFormat(pod *corev1.Pod, network *nadv1.NetworkSelectionElement) string {
@@ -642,11 +1345,17 @@ func newAttachments(
 func attachmentsToDelete(
 	networkStatus []nadv1.NetworkStatus,
 	currentIndexedNetworkStatus map[string]nadv1.NetworkSelectionElement,
+	indexedDynamicAttachments map[string]annotations.DynamicAttachment,
 ) []nadv1.NetworkSelectionElement {
 	var attachmentsToRemove []nadv1.NetworkSelectionElement
 	for i := range networkStatus {
 		networkNamespace, networkName, _ := separateNamespaceAndName(annotations.NetworkStatusIndexKey(networkStatus[i]))
 		if _, wasFound := currentIndexedNetworkStatus[annotations.NetworkStatusIndexKey(networkStatus[i])]; !wasFound {
+			// remove the attachment using the attributes it was plugged with, when those are known
+			if dynamicAttachment, wasRecorded := indexedDynamicAttachments[annotations.NetworkStatusIndexKey(networkStatus[i])]; wasRecorded {
+				attachmentsToRemove = append(attachmentsToRemove, dynamicAttachment.NetworkSelectionElement)
+				continue
+			}
 			attachmentsToRemove = append(attachmentsToRemove, nadv1.NetworkSelectionElement{
 				Name:             networkName,
 				Namespace:        networkNamespace,
@@ -656,3 +1365,43 @@ func attachmentsToDelete(
 	}
 	return attachmentsToRemove
 }
+
+// staleAttachments returns the plugged attachments whose network selection element differs
+// from the one recorded when they were plugged, or whose network-attachment-definition
+// configuration changed - provided its spec change policy is to re-plug.
+func (pnc *PodNetworksController) staleAttachments(
+	networkSelectionElements []nadv1.NetworkSelectionElement,
+	currentIndexedNetworkStatus map[string]nadv1.NetworkStatus,
+	indexedDynamicAttachments map[string]annotations.DynamicAttachment,
+) []nadv1.NetworkSelectionElement {
+	var attachmentsToReplug []nadv1.NetworkSelectionElement
+	for i := range networkSelectionElements {
+		attachmentKey := annotations.NetworkSelectionElementIndexKey(networkSelectionElements[i])
+		if _, isPlugged := currentIndexedNetworkStatus[attachmentKey]; !isPlugged {
+			continue
+		}
+		dynamicAttachment, wasRecorded := indexedDynamicAttachments[attachmentKey]
+		if !wasRecorded {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(dynamicAttachment.NetworkSelectionElement, networkSelectionElements[i]) ||
+			pnc.isStale(dynamicAttachment) {
+			attachmentsToReplug = append(attachmentsToReplug, networkSelectionElements[i])
+		}
+	}
+	return attachmentsToReplug
+}
+
+func recordedAttachments(
+	attachments []nadv1.NetworkSelectionElement,
+	indexedDynamicAttachments map[string]annotations.DynamicAttachment,
+) []nadv1.NetworkSelectionElement {
+	previousAttachments := make([]nadv1.NetworkSelectionElement, 0, len(attachments))
+	for i := range attachments {
+		previousAttachments = append(
+			previousAttachments,
+			indexedDynamicAttachments[annotations.NetworkSelectionElementIndexKey(attachments[i])].NetworkSelectionElement,
+		)
+	}
+	return previousAttachments
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 
 	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
@@ -91,13 +93,14 @@ var _ = Describe("Dynamic Attachment controller", func() {
 			)
 			cniArgs := &map[string]string{"foo": "bar"}
 			var (
-				eventRecorder *record.FakeRecorder
-				k8sClient     *fake.Clientset
-				pod           *corev1.Pod
-				networkToAdd  string
-				networkToAdd1 string
-				stopChannel   chan struct{}
-				nadClient     nadclient.Interface
+				eventRecorder  *record.FakeRecorder
+				k8sClient      *fake.Clientset
+				pod            *corev1.Pod
+				networkToAdd   string
+				networkToAdd1  string
+				stopChannel    chan struct{}
+				nadClient      nadclient.Interface
+				controllerOpts []Option
 			)
 
 			networkStatusNames := func(statuses []nad.NetworkStatus) []string {
@@ -108,6 +111,14 @@ var _ = Describe("Dynamic Attachment controller", func() {
 				return names
 			}
 
+			podDynamicNetworksCondition := func() (*corev1.PodCondition, error) {
+				updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return podCondition(updatedPod, DynamicNetworksReady), nil
+			}
+
 			BeforeEach(func() {
 				pod = podSpec(podName, namespace, podUID, networkName)
 				networkToAdd = fmt.Sprintf("%s-2", networkName)
@@ -123,6 +134,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 				DeferCleanup(func() { close(stopChannel) })
 				const maxEvents = 5
 				eventRecorder = record.NewFakeRecorder(maxEvents)
+				controllerOpts = nil
 			})
 
 			JustBeforeEach(func() {
@@ -140,6 +152,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 							networkConfig(multuscni.CmdAdd, "net2", ""),
 							networkConfig(multuscni.CmdDel, "net2", ""),
 						),
+						controllerOpts...,
 					)).NotTo(BeNil())
 				Expect(func() []nad.NetworkStatus {
 					updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
@@ -193,6 +206,44 @@ var _ = Describe("Dynamic Attachment controller", func() {
 						ifaceStatusForDefaultNamespace(networkName, "net0", ""),
 						ifaceStatusForDefaultNamespace(networkToAdd, "net1", macAddr)))
 				})
+
+				It("the pod reports its dynamic networks are ready", func() {
+					Eventually(podDynamicNetworksCondition).Should(And(
+						Not(BeNil()),
+						HaveField("Status", corev1.ConditionTrue),
+						HaveField("Reason", AttachmentsReadyReason),
+					))
+				})
+			})
+
+			When("multiple workers reconcile the pods", func() {
+				BeforeEach(func() {
+					const workers = 3
+					controllerOpts = []Option{WithWorkers(workers)}
+				})
+
+				JustBeforeEach(func() {
+					_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+						context.TODO(),
+						updatePodSpec(pod, networkName, networkToAdd, networkToAdd1),
+						metav1.UpdateOptions{})
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("the attachments of a pod are added in order", func() {
+					Eventually(<-eventRecorder.Events).Should(Equal(fmt.Sprintf(
+						"Normal AddedInterface pod [%s]: added interface %s to network: %s",
+						annotations.NamespacedName(namespace, podName),
+						"net1",
+						networkToAdd,
+					)))
+					Eventually(<-eventRecorder.Events).Should(Equal(fmt.Sprintf(
+						"Normal AddedInterface pod [%s]: added interface %s to network: %s",
+						annotations.NamespacedName(namespace, podName),
+						"net2",
+						networkToAdd1,
+					)))
+				})
 			})
 
 			When("an attachment is removed from the pod's network annotations", func() {
@@ -373,6 +424,72 @@ var _ = Describe("Dynamic Attachment controller", func() {
 				})
 			})
 
+			When("the attributes of an attachment plugged by the controller are modified", func() {
+				const updatedMacAddr = "02:03:04:05:06:08"
+
+				JustBeforeEach(func() {
+					pod = updatePodSpec(pod)
+					pluggedAttachment := nad.NetworkSelectionElement{
+						Name:             networkToAdd1,
+						Namespace:        namespace,
+						InterfaceRequest: "net2",
+						MacRequest:       macAddr,
+					}
+					modifiedAttachment := pluggedAttachment
+					modifiedAttachment.MacRequest = updatedMacAddr
+
+					serelizedNetSelectionElements, _ := json.Marshal(
+						append(generateNetworkSelectionElements(namespace, networkName), modifiedAttachment))
+					pod.Annotations[nad.NetworkAttachmentAnnot] = string(serelizedNetSelectionElements)
+					serializedStatus, _ := json.Marshal([]nad.NetworkStatus{
+						ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+						ifaceStatusForDefaultNamespace(networkToAdd1, "net2", macAddr),
+					})
+					pod.Annotations[nad.NetworkStatusAnnot] = string(serializedStatus)
+					serializedAttachments, _ := json.Marshal([]annotations.DynamicAttachment{{NetworkSelectionElement: pluggedAttachment}})
+					pod.Annotations[annotations.DynamicAttachmentsAnnot] = string(serializedAttachments)
+
+					_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+						context.TODO(),
+						pod,
+						metav1.UpdateOptions{})
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("a `RemovedInterface` event and then an `AddedInterface` event with the new attributes are seen", func() {
+					expectedRemoveInterfaceEvent := fmt.Sprintf(
+						"Normal RemovedInterface pod [%s]: removed interface %s from network: %s",
+						annotations.NamespacedName(namespace, podName),
+						"net2",
+						networkToAdd1,
+					)
+					Eventually(<-eventRecorder.Events).Should(Equal(expectedRemoveInterfaceEvent))
+					expectedAddInterfaceEvent := fmt.Sprintf(
+						"Normal AddedInterface pod [%s]: added interface %s to network: %s(ips: [], mac: %s, cni-args: <nil>)",
+						annotations.NamespacedName(namespace, podName),
+						"net2",
+						networkToAdd1,
+						updatedMacAddr,
+					)
+					Eventually(<-eventRecorder.Events).Should(Equal(expectedAddInterfaceEvent))
+				})
+
+				It("the controller records the new attributes of the attachment", func() {
+					Eventually(func() ([]annotations.DynamicAttachment, error) {
+						updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+						if err != nil {
+							return nil, err
+						}
+						return annotations.PodDynamicAttachments(updatedPod)
+					}).Should(ConsistOf(HaveField("NetworkSelectionElement", nad.NetworkSelectionElement{
+						Name:             networkToAdd1,
+						Namespace:        namespace,
+						InterfaceRequest: "net2",
+						MacRequest:       updatedMacAddr,
+					})))
+				})
+			})
+
 			When("a wrong attachment is added to the pod's network annotations with a following correct attachement", func() {
 				JustBeforeEach(func() {
 					pod = updatePodSpec(pod)
@@ -400,9 +517,9 @@ var _ = Describe("Dynamic Attachment controller", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 
-				It("an `FailedAddingInterface` event and then a `FailedRemovingInterface` are seen, the network status is not updated", func() {
+				It("an `FailedAddingInterfaceTransient` event and then a `FailedRemovingInterfaceTransient` are seen, the network status is not updated", func() {
 					expectedAddInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedAddingInterface pod [%s]: failed adding interface %s to network: %s",
+						"Warning FailedAddingInterfaceTransient pod [%s]: failed adding interface %s to network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net-non-existing",
 						networkToAdd,
@@ -411,7 +528,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 
 					// try to remove interface added failed
 					expectedRemoveInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedRemovingInterface pod [%s]: failed removing interface %s from network: %s",
+						"Warning FailedRemovingInterfaceTransient pod [%s]: failed removing interface %s from network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net-non-existing",
 						networkToAdd,
@@ -420,7 +537,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 
 					// reconciliation requeued without adding the next interface (net1).
 					expectedAddNextInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedAddingInterface pod [%s]: failed adding interface %s to network: %s",
+						"Warning FailedAddingInterfaceTransient pod [%s]: failed adding interface %s to network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net-non-existing",
 						networkToAdd,
@@ -471,7 +588,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 
-				It("an `AddedInterface` event is seen, followed by a `FailedAddingInterface` event", func() {
+				It("an `AddedInterface` event is seen, followed by a `FailedAddingInterfaceTransient` event", func() {
 					expectedAddInterfaceEvent := fmt.Sprintf(
 						"Normal AddedInterface pod [%s]: added interface %s to network: %s",
 						annotations.NamespacedName(namespace, podName),
@@ -481,7 +598,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 					Eventually(<-eventRecorder.Events).Should(Equal(expectedAddInterfaceEvent))
 
 					expectedAddInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedAddingInterface pod [%s]: failed adding interface %s to network: %s",
+						"Warning FailedAddingInterfaceTransient pod [%s]: failed adding interface %s to network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net-non-existing",
 						networkToAdd,
@@ -504,6 +621,15 @@ var _ = Describe("Dynamic Attachment controller", func() {
 						ifaceStatusForDefaultNamespace(networkName, "net0", ""),
 						ifaceStatusForDefaultNamespace(networkToAdd, "net1", macAddr)))
 				})
+
+				It("the pod reports the attachment pending to be plugged", func() {
+					Eventually(podDynamicNetworksCondition).Should(And(
+						Not(BeNil()),
+						HaveField("Status", corev1.ConditionFalse),
+						HaveField("Reason", AttachmentsPendingReason),
+						HaveField("Message", ContainSubstring(fmt.Sprintf("%s/%s/net-non-existing", namespace, networkToAdd))),
+					))
+				})
 			})
 
 			When("an attachment is removed followed by a failing removal of another attachment", func() {
@@ -533,7 +659,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 						ifaceStatusForDefaultNamespace(networkToAdd, "net1", macAddr)))
 				})
 
-				It("a `RemovedInterface` event is seen followed by a `FailedRemovingInterface` event", func() {
+				It("a `RemovedInterface` event is seen followed by a `FailedRemovingInterfaceTransient` event", func() {
 					expectedRemoveInterfaceEvent := fmt.Sprintf(
 						"Normal RemovedInterface pod [%s]: removed interface %s from network: %s",
 						annotations.NamespacedName(namespace, podName),
@@ -543,7 +669,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 					Eventually(<-eventRecorder.Events).Should(Equal(expectedRemoveInterfaceEvent))
 
 					expectedRemoveInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedRemovingInterface pod [%s]: failed removing interface %s from network: %s",
+						"Warning FailedRemovingInterfaceTransient pod [%s]: failed removing interface %s from network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net1",
 						networkToAdd,
@@ -594,7 +720,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 
 				It("a `RemovedInterface` event is seen", func() {
 					expectedRemoveInterfaceFailedEvent := fmt.Sprintf(
-						"Warning FailedRemovingInterface pod [%s]: failed removing interface %s from network: %s",
+						"Warning FailedRemovingInterfaceTransient pod [%s]: failed removing interface %s from network: %s",
 						annotations.NamespacedName(namespace, podName),
 						"net1",
 						networkToAdd,
@@ -643,7 +769,7 @@ var _ = Describe("Dynamic Attachment controller", func() {
 						metav1.UpdateOptions{})
 					Expect(err).NotTo(HaveOccurred())
 					expectedError := errors.New("someerror")
-					k8sClient.PrependReactor("update", "pods", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+					k8sClient.PrependReactor("patch", "pods", func(_ k8stesting.Action) (bool, runtime.Object, error) {
 						return true, nil, expectedError
 					})
 				})
@@ -674,6 +800,53 @@ var _ = Describe("Dynamic Attachment controller", func() {
 	})
 })
 
+var _ = Describe("The dynamic attachment controller workers liveness", func() {
+	const livenessWindow = time.Minute
+
+	var podController *PodNetworksController
+
+	BeforeEach(func() {
+		podController = &PodNetworksController{
+			workqueue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			livenessWindow: livenessWindow,
+		}
+		DeferCleanup(podController.workqueue.ShutDown)
+	})
+
+	It("is alive when there is no pending work", func() {
+		podController.lastProgress.Store(time.Now().Add(-2 * livenessWindow).UnixNano())
+		Expect(podController.WorkersAlive(context.Background())).To(Succeed())
+	})
+
+	It("is alive when there is pending work and an item was recently processed", func() {
+		podController.workqueue.Add("ns1/pod1")
+		podController.recordProgress()
+		Expect(podController.WorkersAlive(context.Background())).To(Succeed())
+	})
+
+	It("is not alive when there is pending work but no item was processed within the liveness window", func() {
+		podController.workqueue.Add("ns1/pod1")
+		podController.lastProgress.Store(time.Now().Add(-2 * livenessWindow).UnixNano())
+		Expect(podController.WorkersAlive(context.Background())).To(MatchError(HavePrefix("1 items pending in the queue")))
+	})
+
+	It("is not alive when there is pending work but one of the workers processed no item within the liveness window", func() {
+		const workers = 2
+		podController.workerHeartbeats = make([]atomic.Int64, workers)
+		podController.workqueue.Add("ns1/pod1")
+		podController.recordHeartbeat(0)
+		podController.workerHeartbeats[1].Store(time.Now().Add(-2 * livenessWindow).UnixNano())
+		Expect(podController.WorkersAlive(context.Background())).To(MatchError(ContainSubstring("worker 1 processed none")))
+	})
+
+	It("is alive when there is no pending work, whatever the workers heartbeats", func() {
+		const workers = 2
+		podController.workerHeartbeats = make([]atomic.Int64, workers)
+		podController.workerHeartbeats[1].Store(time.Now().Add(-2 * livenessWindow).UnixNano())
+		Expect(podController.WorkersAlive(context.Background())).To(Succeed())
+	})
+})
+
 func networkConfig(cmd, ifaceName, mac string) fakemultusclient.NetworkConfig {
 	const cniVersion = "1.0.0"
 	return fakemultusclient.NetworkConfig{
@@ -701,7 +874,8 @@ func newDummyPodController(
 	stopChannel chan struct{},
 	recorder record.EventRecorder,
 	containerRuntime ContainerRuntime,
-	multusClient multuscni.Client) (*dummyPodController, error) {
+	multusClient multuscni.Client,
+	opts ...Option) (*dummyPodController, error) {
 	const noResyncPeriod = 0
 	netAttachDefInformerFactory := nadinformers.NewSharedInformerFactory(nadClient, noResyncPeriod)
 	podInformerFactory := v1coreinformerfactory.NewSharedInformerFactory(k8sClient, noResyncPeriod)
@@ -714,7 +888,8 @@ func newDummyPodController(
 		k8sClient,
 		nadClient,
 		containerRuntime,
-		multusClient)
+		multusClient,
+		opts...)
 
 	alwaysReady := func() bool { return true }
 	podController.arePodsSynched = alwaysReady
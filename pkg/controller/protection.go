@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+const (
+	netAttachDefProtectionQueueName = "network-attachment-definitions-protection"
+	inUseFinalizerDomain            = "dynamic-networks-controller.k8s.cni.cncf.io"
+	podsByDynamicAttachmentIndex    = "byDynamicAttachment"
+
+	// for how long the finalizers are released on shutdown, when the protection is disabled
+	finalizersReleaseTimeout = 5 * time.Second
+	// how often a network-attachment-definition being deleted is checked for the finalizers of
+	// the nodes removed since
+	removedNodesCheckPeriod = time.Minute
+)
+
+// WithNetAttachDefProtection guards the network-attachment-definitions referenced by the dynamic
+// attachments of the pods on the given node against deletion, using a finalizer per node.
+func WithNetAttachDefProtection(nodeName string) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.nodeName = nodeName
+		pnc.netAttachDefFinalizer = InUseFinalizer(nodeName)
+	}
+}
+
+// WithoutNetAttachDefProtection releases the network-attachment-definitions the given node protected
+// against deletion, while the protection was enabled.
+func WithoutNetAttachDefProtection(nodeName string) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.nodeName = nodeName
+		pnc.releasedFinalizer = InUseFinalizer(nodeName)
+	}
+}
+
+// InUseFinalizer returns the finalizer set on the network-attachment-definitions in use by
+// the dynamic attachments of the pods running on the given node.
+func InUseFinalizer(nodeName string) string {
+	// the name segment of a qualified name is at most 63 characters long
+	const (
+		maxNameLength = 63
+		digestLength  = 8
+	)
+	if len(nodeName) > maxNameLength {
+		digest := sha256.Sum256([]byte(nodeName))
+		nodeName = nodeName[:maxNameLength-digestLength-1] + "-" + hex.EncodeToString(digest[:])[:digestLength]
+	}
+	return fmt.Sprintf("%s/%s", inUseFinalizerDomain, nodeName)
+}
+
+func (pnc *PodNetworksController) setupNetAttachDefProtection(podInformer, nadInformer cache.SharedIndexInformer) error {
+	pnc.netAttachDefQueue = workqueue.NewRateLimitingQueueWithConfig(
+		workqueue.DefaultControllerRateLimiter(),
+		workqueue.RateLimitingQueueConfig{
+			Name:            netAttachDefProtectionQueueName,
+			MetricsProvider: pnc.metrics.WorkqueueMetricsProvider(),
+		},
+	)
+
+	if err := podInformer.AddIndexers(cache.Indexers{podsByDynamicAttachmentIndex: podsByDynamicAttachment}); err != nil {
+		return fmt.Errorf("error indexing the pods by dynamically attached network: %v", err)
+	}
+
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, isPod := oldObj.(*corev1.Pod)
+			if !isPod {
+				return
+			}
+			newPod, isPod := newObj.(*corev1.Pod)
+			if !isPod {
+				return
+			}
+			if oldPod.GetAnnotations()[annotations.DynamicAttachmentsAnnot] != newPod.GetAnnotations()[annotations.DynamicAttachmentsAnnot] {
+				pnc.enqueueDynamicallyAttachedNetworks(oldPod)
+				pnc.enqueueDynamicallyAttachedNetworks(newPod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+				obj = tombstone.Obj
+			}
+			if pod, isPod := obj.(*corev1.Pod); isPod {
+				pnc.enqueueDynamicallyAttachedNetworks(pod)
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("error setting the network-attachment-definition protection pod event handlers: %v", err)
+	}
+
+	if _, err := nadInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    pnc.enqueueNetAttachDef,
+		UpdateFunc: func(_, newObj interface{}) { pnc.enqueueNetAttachDef(newObj) },
+	}); err != nil {
+		return fmt.Errorf("error setting the network-attachment-definition protection event handlers: %v", err)
+	}
+	return nil
+}
+
+// podsByDynamicAttachment indexes the pods by the network-attachment-definitions of their dynamic attachments.
+func podsByDynamicAttachment(obj interface{}) ([]string, error) {
+	pod, isPod := obj.(*corev1.Pod)
+	if !isPod {
+		return nil, nil
+	}
+	return dynamicallyAttachedNetworks(pod), nil
+}
+
+func dynamicallyAttachedNetworks(pod *corev1.Pod) []string {
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+	if err != nil {
+		klog.V(logging.Debug).InfoS("failed to read the dynamic attachments", "pod", klog.KObj(pod), "err", err)
+		return nil
+	}
+
+	var networks []string
+	for i := range dynamicAttachments {
+		attachment := dynamicAttachments[i].NetworkSelectionElement
+		network := annotations.NamespacedName(attachment.Namespace, attachment.Name)
+		if !slices.Contains(networks, network) {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func (pnc *PodNetworksController) enqueueDynamicallyAttachedNetworks(pod *corev1.Pod) {
+	for _, network := range dynamicallyAttachedNetworks(pod) {
+		pnc.netAttachDefQueue.Add(network)
+	}
+}
+
+func (pnc *PodNetworksController) enqueueNetAttachDef(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.ErrorS(err, "failed to compute the network-attachment-definition key")
+		return
+	}
+	pnc.netAttachDefQueue.Add(key)
+}
+
+func (pnc *PodNetworksController) netAttachDefProtectionWorker(ctx context.Context) {
+	for pnc.processNextNetAttachDef(ctx) {
+	}
+}
+
+func (pnc *PodNetworksController) processNextNetAttachDef(ctx context.Context) bool {
+	queueItem, shouldQuit := pnc.netAttachDefQueue.Get()
+	if shouldQuit {
+		return false
+	}
+	defer pnc.netAttachDefQueue.Done(queueItem)
+
+	key := queueItem.(string)
+	// the finalizer is retried until it is in sync: giving up would let a network-attachment-definition
+	// in use be deleted - or one no longer in use be held forever
+	if err := pnc.syncNetAttachDefProtection(ctx, key); err != nil {
+		klog.ErrorS(err, "re-queued the protection of the network-attachment-definition", "networkAttachmentDefinition", key)
+		pnc.netAttachDefQueue.AddRateLimited(key)
+		return true
+	}
+	pnc.netAttachDefQueue.Forget(key)
+	return true
+}
+
+// syncNetAttachDefProtection sets the node finalizer on the network-attachment-definition while
+// it is in use by the dynamic attachments of pods on the node, and removes it otherwise.
+func (pnc *PodNetworksController) syncNetAttachDefProtection(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	netAttachDef, err := pnc.netAttachDefLister.NetworkAttachmentDefinitions(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if netAttachDef.GetDeletionTimestamp() != nil {
+		if err := pnc.releaseRemovedNodesFinalizers(ctx, netAttachDef); err != nil {
+			return err
+		}
+	}
+
+	pods, err := pnc.podsInformer.GetIndexer().ByIndex(podsByDynamicAttachmentIndex, key)
+	if err != nil {
+		return err
+	}
+	podNames := make([]string, 0, len(pods))
+	for _, obj := range pods {
+		if pod, isPod := obj.(*corev1.Pod); isPod {
+			podNames = append(podNames, annotations.NamespacedName(pod.GetNamespace(), pod.GetName()))
+		}
+	}
+	sort.Strings(podNames)
+
+	isInUse := len(podNames) > 0
+	hasFinalizer := slices.Contains(netAttachDef.GetFinalizers(), pnc.netAttachDefFinalizer)
+	switch {
+	case isInUse && netAttachDef.GetDeletionTimestamp() != nil:
+		pnc.Eventf(
+			netAttachDefReference(netAttachDef),
+			corev1.EventTypeWarning,
+			"DeletionBlocked",
+			"network-attachment-definition %s is in use by pods %v on node %s",
+			key,
+			podNames,
+			pnc.nodeName,
+		)
+	case isInUse && !hasFinalizer:
+		klog.InfoS("protecting the network-attachment-definition in use", "networkAttachmentDefinition", key, "pods", podNames)
+		return pnc.updateNetAttachDefFinalizers(ctx, netAttachDef, func(finalizers []string) []string {
+			return append(finalizers, pnc.netAttachDefFinalizer)
+		})
+	case !isInUse && hasFinalizer:
+		klog.InfoS("releasing the network-attachment-definition no longer in use", "networkAttachmentDefinition", key)
+		return pnc.updateNetAttachDefFinalizers(ctx, netAttachDef, func(finalizers []string) []string {
+			return slices.DeleteFunc(finalizers, func(finalizer string) bool {
+				return finalizer == pnc.netAttachDefFinalizer
+			})
+		})
+	}
+	return nil
+}
+
+// releaseRemovedNodesFinalizers removes the finalizers of the nodes which no longer exist from the
+// network-attachment-definition being deleted: their controller is not around to release it anymore.
+// The network-attachment-definition is checked again later while other nodes hold it, since these
+// may be removed in the meantime.
+func (pnc *PodNetworksController) releaseRemovedNodesFinalizers(
+	ctx context.Context,
+	netAttachDef *nadv1.NetworkAttachmentDefinition,
+) error {
+	var otherNodesFinalizers []string
+	for _, finalizer := range netAttachDef.GetFinalizers() {
+		if strings.HasPrefix(finalizer, inUseFinalizerDomain+"/") && finalizer != pnc.netAttachDefFinalizer {
+			otherNodesFinalizers = append(otherNodesFinalizers, finalizer)
+		}
+	}
+	if len(otherNodesFinalizers) == 0 {
+		return nil
+	}
+
+	nodes, err := pnc.k8sClientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the nodes holding the network-attachment-definition: %v", err)
+	}
+	existingNodesFinalizers := make(map[string]bool, len(nodes.Items))
+	for i := range nodes.Items {
+		existingNodesFinalizers[InUseFinalizer(nodes.Items[i].GetName())] = true
+	}
+	removedNodesFinalizers := slices.DeleteFunc(slices.Clone(otherNodesFinalizers), func(finalizer string) bool {
+		return existingNodesFinalizers[finalizer]
+	})
+
+	key := annotations.NamespacedName(netAttachDef.GetNamespace(), netAttachDef.GetName())
+	if len(removedNodesFinalizers) < len(otherNodesFinalizers) {
+		pnc.netAttachDefQueue.AddAfter(key, removedNodesCheckPeriod)
+	}
+	if len(removedNodesFinalizers) == 0 {
+		return nil
+	}
+	klog.InfoS(
+		"releasing the network-attachment-definition held by removed nodes",
+		"networkAttachmentDefinition", key,
+		"finalizers", removedNodesFinalizers,
+	)
+	return pnc.updateNetAttachDefFinalizers(ctx, netAttachDef, func(finalizers []string) []string {
+		return slices.DeleteFunc(finalizers, func(finalizer string) bool {
+			return slices.Contains(removedNodesFinalizers, finalizer)
+		})
+	})
+}
+
+// releaseNetAttachDefs removes the node finalizer from all the network-attachment-definitions, once
+// their protection is disabled.
+func (pnc *PodNetworksController) releaseNetAttachDefs(ctx context.Context) error {
+	netAttachDefs, err := pnc.netAttachDefLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list the network-attachment-definitions to release: %v", err)
+	}
+
+	var errs []error
+	for _, netAttachDef := range netAttachDefs {
+		if !slices.Contains(netAttachDef.GetFinalizers(), pnc.releasedFinalizer) {
+			continue
+		}
+		klog.InfoS(
+			"releasing the network-attachment-definition no longer protected",
+			"networkAttachmentDefinition", klog.KObj(netAttachDef),
+		)
+		if err := pnc.updateNetAttachDefFinalizers(ctx, netAttachDef, func(finalizers []string) []string {
+			return slices.DeleteFunc(finalizers, func(finalizer string) bool {
+				return finalizer == pnc.releasedFinalizer
+			})
+		}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to release the network-attachment-definition %s: %v", klog.KObj(netAttachDef), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (pnc *PodNetworksController) updateNetAttachDefFinalizers(
+	ctx context.Context,
+	netAttachDef *nadv1.NetworkAttachmentDefinition,
+	updateFinalizers func([]string) []string,
+) error {
+	netAttachDefsClient := pnc.nadClientSet.K8sCniCncfIoV1().NetworkAttachmentDefinitions(netAttachDef.GetNamespace())
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentNetAttachDef, err := netAttachDefsClient.Get(ctx, netAttachDef.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		currentNetAttachDef.SetFinalizers(updateFinalizers(currentNetAttachDef.GetFinalizers()))
+		_, err = netAttachDefsClient.Update(ctx, currentNetAttachDef, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func netAttachDefReference(netAttachDef *nadv1.NetworkAttachmentDefinition) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion:      nadv1.SchemeGroupVersion.String(),
+		Kind:            "NetworkAttachmentDefinition",
+		Namespace:       netAttachDef.GetNamespace(),
+		Name:            netAttachDef.GetName(),
+		UID:             netAttachDef.GetUID(),
+		ResourceVersion: netAttachDef.GetResourceVersion(),
+	}
+}
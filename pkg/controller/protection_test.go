@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
+	fakenadclient "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned/fake"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The network-attachment-definitions protection", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		nodeName    = "node1"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var (
+		eventRecorder *record.FakeRecorder
+		k8sClient     *fake.Clientset
+		nadClient     nadclient.Interface
+		pod           *corev1.Pod
+		networkToAdd  string
+		// the number of finalizer updates failing before one succeeds
+		failingFinalizerUpdates int
+	)
+
+	netAttachDefFinalizers := func() ([]string, error) {
+		netAttachDef, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Get(
+			context.TODO(),
+			networkToAdd,
+			metav1.GetOptions{},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return netAttachDef.GetFinalizers(), nil
+	}
+
+	updatePodNetworks := func(networkSelectionElements ...nad.NetworkSelectionElement) {
+		currentPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		serializedNetSelectionElements, _ := json.Marshal(networkSelectionElements)
+		currentPod.Annotations[nad.NetworkAttachmentAnnot] = string(serializedNetSelectionElements)
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), currentPod, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		pod = podSpec(podName, namespace, podUID, networkName)
+		networkToAdd = fmt.Sprintf("%s-3", networkName)
+
+		var err error
+		nadClient, err = newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkToAdd, namespace, dummyNetSpec(networkToAdd, cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+		failingFinalizerUpdates = 0
+		nadClient.(*fakenadclient.Clientset).PrependReactor(
+			"update",
+			"network-attachment-definitions",
+			func(_ k8stesting.Action) (bool, runtime.Object, error) {
+				if failingFinalizerUpdates == 0 {
+					return false, nil, nil
+				}
+				failingFinalizerUpdates--
+				return true, nil, apierrors.NewServiceUnavailable("the API server is overloaded")
+			})
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		eventRecorder = record.NewFakeRecorder(maxEvents)
+
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				eventRecorder,
+				fakecri.NewFakeRuntime(*pod),
+				fakemultusclient.NewFakeClient(
+					networkConfig(multuscni.CmdAdd, "net2", ""),
+					networkConfig(multuscni.CmdDel, "net2", ""),
+				),
+				WithNetAttachDefProtection(nodeName),
+			)).NotTo(BeNil())
+	})
+
+	JustBeforeEach(func() {
+		updatePodNetworks(
+			append(
+				generateNetworkSelectionElements(namespace, networkName),
+				nad.NetworkSelectionElement{Name: networkToAdd, Namespace: namespace, InterfaceRequest: "net2"},
+			)...,
+		)
+		Eventually(eventRecorder.Events).Should(Receive(HavePrefix("Normal AddedInterface")))
+	})
+
+	It("sets the node finalizer on the network-attachment-definitions in use", func() {
+		Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName)))
+	})
+
+	When("the finalizer updates keep failing for a while", func() {
+		BeforeEach(func() {
+			failingFinalizerUpdates = 5
+		})
+
+		It("sets the node finalizer once they succeed", func() {
+			Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName)))
+		})
+	})
+
+	It("removes the node finalizer once the network-attachment-definition is no longer in use", func() {
+		Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName)))
+
+		updatePodNetworks(generateNetworkSelectionElements(namespace, networkName)...)
+		Eventually(netAttachDefFinalizers).Should(BeEmpty())
+	})
+
+	It("reports the pods holding a network-attachment-definition being deleted", func() {
+		Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName)))
+
+		netAttachDef, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Get(
+			context.TODO(),
+			networkToAdd,
+			metav1.GetOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		now := metav1.Now()
+		netAttachDef.SetDeletionTimestamp(&now)
+		_, err = nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Update(
+			context.TODO(),
+			netAttachDef,
+			metav1.UpdateOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(eventRecorder.Events).Should(Receive(Equal(fmt.Sprintf(
+			"Warning DeletionBlocked network-attachment-definition %s is in use by pods [%s] on node %s",
+			annotations.NamespacedName(namespace, networkToAdd),
+			annotations.NamespacedName(namespace, podName),
+			nodeName,
+		))))
+	})
+
+	It("releases the network-attachment-definition being deleted from the nodes no longer existing", func() {
+		const (
+			existingNodeName = "node2"
+			removedNodeName  = "node3"
+		)
+		Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName)))
+
+		_, err := k8sClient.CoreV1().Nodes().Create(
+			context.TODO(),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: existingNodeName}},
+			metav1.CreateOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		netAttachDef, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Get(
+			context.TODO(),
+			networkToAdd,
+			metav1.GetOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		now := metav1.Now()
+		netAttachDef.SetDeletionTimestamp(&now)
+		netAttachDef.SetFinalizers(append(
+			netAttachDef.GetFinalizers(),
+			InUseFinalizer(existingNodeName),
+			InUseFinalizer(removedNodeName),
+		))
+		_, err = nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Update(
+			context.TODO(),
+			netAttachDef,
+			metav1.UpdateOptions{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(netAttachDefFinalizers).Should(ConsistOf(InUseFinalizer(nodeName), InUseFinalizer(existingNodeName)))
+	})
+})
+
+var _ = Describe("The network-attachment-definitions protection, once disabled", func() {
+	const (
+		cniVersion    = "0.3.0"
+		namespace     = "default"
+		networkName   = "tiny-net"
+		nodeName      = "node1"
+		otherNodeName = "node2"
+	)
+
+	var nadClient nadclient.Interface
+
+	BeforeEach(func() {
+		protectedNetAttachDef := netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion))
+		protectedNetAttachDef.SetFinalizers([]string{InUseFinalizer(nodeName), InUseFinalizer(otherNodeName)})
+
+		var err error
+		nadClient, err = newFakeNetAttachDefClient(protectedNetAttachDef)
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		Expect(
+			newDummyPodController(
+				fake.NewSimpleClientset(),
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(),
+				fakemultusclient.NewFakeClient(),
+				WithoutNetAttachDefProtection(nodeName),
+			)).NotTo(BeNil())
+	})
+
+	It("releases the network-attachment-definitions protected by the node", func() {
+		Eventually(func() ([]string, error) {
+			netAttachDef, err := nadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(namespace).Get(
+				context.TODO(),
+				networkName,
+				metav1.GetOptions{},
+			)
+			if err != nil {
+				return nil, err
+			}
+			return netAttachDef.GetFinalizers(), nil
+		}).Should(ConsistOf(InUseFinalizer(otherNodeName)))
+	})
+})
+
+var _ = DescribeTable("The in use finalizer", func(nodeName string, expectedFinalizer string) {
+	finalizer := InUseFinalizer(nodeName)
+	Expect(finalizer).To(Equal(expectedFinalizer))
+	Expect(len(finalizer[strings.Index(finalizer, "/")+1:])).To(BeNumerically("<=", 63))
+},
+	Entry("is named after the node", "node1", "dynamic-networks-controller.k8s.cni.cncf.io/node1"),
+	Entry(
+		"is shortened using a digest for long node names",
+		"a-very-long-node-name.with-its-domain.in-a-very-large-datacenter.example.com",
+		"dynamic-networks-controller.k8s.cni.cncf.io/a-very-long-node-name.with-its-domain.in-a-very-large--5fc10866",
+	),
+)
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+// WithResyncPeriod periodically re-queues the running pods whose dynamic attachments differ from
+// the requested ones, regardless of the pod updates the controller was notified of.
+func WithResyncPeriod(period time.Duration) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.resyncPeriod = period
+	}
+}
+
+// resync re-queues the running pods whose dynamic attachments differ from the requested ones. The
+// pods backing off are left alone: they are re-queued once their backoff expires. So are the pods
+// outdated in the cache: their attachments may have been reconciled already, and their update
+// re-queues them if need be.
+func (pnc *PodNetworksController) resync(ctx context.Context) {
+	pods, err := pnc.podsLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list the pods to resync")
+		return
+	}
+	for _, pod := range pods {
+		if pnc.ignoreHostNetworkedPods(pod) || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		namespacedName := annotations.NamespacedName(pod.GetNamespace(), pod.GetName())
+		if pnc.numRequeues(namespacedName) > 0 {
+			continue
+		}
+		isOutOfSync, err := pnc.isOutOfSync(pod)
+		if err != nil {
+			klog.ErrorS(err, "failed to compare the requested and actual attachments", "pod", klog.KObj(pod))
+			continue
+		}
+		if !isOutOfSync {
+			continue
+		}
+		isCurrent, err := pnc.isCachedPodCurrent(ctx, pod)
+		if err != nil {
+			klog.ErrorS(err, "failed to check whether the cached pod is current", "pod", klog.KObj(pod))
+			continue
+		}
+		if !isCurrent {
+			klog.V(logging.Debug).InfoS("pod is outdated in the cache: skipping its resync", "pod", klog.KObj(pod))
+			continue
+		}
+		klog.V(logging.Debug).InfoS("pod added to the resync", "pod", klog.KObj(pod))
+		pnc.workqueue.Add(namespacedName)
+	}
+}
+
+// isOutOfSync tells whether attachments of the pod are to be added, removed, or re-plugged - or its
+// DynamicNetworksReady condition does not reflect its plugged attachments.
+func (pnc *PodNetworksController) isOutOfSync(pod *corev1.Pod) (bool, error) {
+	pending, err := pendingAttachments(pod)
+	if err != nil {
+		return false, err
+	}
+	if len(pending) > 0 || !isConditionCurrent(pod) {
+		return true, nil
+	}
+
+	networkSelectionElements, networkStatus, err := getPodNetworks(pod)
+	if err != nil {
+		return false, err
+	}
+	dynamicAttachments, err := annotations.PodDynamicAttachments(pod)
+	if err != nil {
+		return false, err
+	}
+	return len(pnc.staleAttachments(
+		networkSelectionElements,
+		annotations.IndexNetworkStatus(networkStatus),
+		annotations.IndexDynamicAttachments(dynamicAttachments),
+	)) > 0, nil
+}
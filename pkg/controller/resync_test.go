@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The level-triggered reconciliation", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var k8sClient *fake.Clientset
+
+	podNetworkStatus := func() ([]nad.NetworkStatus, error) {
+		updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return annotations.PodDynamicNetworkStatus(updatedPod)
+	}
+
+	podReadyCondition := func() (*corev1.PodCondition, error) {
+		updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return podCondition(updatedPod, DynamicNetworksReady), nil
+	}
+
+	startController := func(pod *corev1.Pod, runtime *fakecri.Runtime, multusClient multuscni.Client, opts ...Option) {
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 50
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				runtime,
+				multusClient,
+				opts...,
+			)).NotTo(BeNil())
+	}
+
+	When("the resync is enabled", func() {
+		const resyncPeriod = 100 * time.Millisecond
+
+		var multusClient *blockingMultusClient
+
+		BeforeEach(func() {
+			pod := podSpec(podName, namespace, podUID, networkName)
+			pod.Status.Phase = corev1.PodRunning
+			multusClient = newBlockingMultusClient(
+				"",
+				fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net0", "")),
+			)
+			startController(pod, fakecri.NewFakeRuntime(*pod), multusClient, WithResyncPeriod(resyncPeriod))
+		})
+
+		It("re-plugs the attachments gone from the network-status without the pod networks being updated", func() {
+			pod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			pod.Annotations[nad.NetworkStatusAnnot] = "[]"
+			_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(multusClient.invokedDelegates).Should(Equal([]string{"ADD net0"}))
+			Eventually(podNetworkStatus).Should(ConsistOf(ifaceStatusForDefaultNamespace(networkName, "net0", "")))
+		})
+
+		It("sets the dynamic networks ready condition missing from the pod", func() {
+			Eventually(podReadyCondition).Should(Not(BeNil()))
+			pod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			pod.Status.Conditions = nil
+			_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(podReadyCondition).Should(And(
+				Not(BeNil()),
+				HaveField("Status", corev1.ConditionTrue),
+				HaveField("Reason", AttachmentsReadyReason),
+			))
+		})
+	})
+
+	When("a pod starts running", func() {
+		BeforeEach(func() {
+			otherPod := podSpec("other-pod", namespace, "ghi-jkl")
+			pod := podSpec(podName, namespace, podUID, networkName)
+			startController(otherPod, fakecri.NewFakeRuntime(*otherPod, *pod), fakemultusclient.NewFakeClient())
+
+			pod.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: DynamicNetworksReady}}
+			pod.Status.Phase = corev1.PodRunning
+			_, err := k8sClient.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("sets its dynamic networks ready condition", func() {
+			Eventually(podReadyCondition).Should(And(
+				Not(BeNil()),
+				HaveField("Status", corev1.ConditionTrue),
+				HaveField("Reason", AttachmentsReadyReason),
+			))
+		})
+	})
+
+	When("a reconciliation keeps failing", func() {
+		const failures = 5
+
+		var multusClient *flakyMultusClient
+
+		BeforeEach(func() {
+			pod := podSpec(podName, namespace, podUID, networkName)
+			multusClient = newFlakyMultusClient(
+				failures,
+				fakemultusclient.NewFakeClient(
+					networkConfig(multuscni.CmdAdd, "net1", ""),
+					networkConfig(multuscni.CmdDel, "net1", ""),
+				),
+			)
+			startController(pod, fakecri.NewFakeRuntime(*pod), multusClient, WithRetryPolicy(
+				ErrorClassTransient,
+				RetryPolicy{MaxRetries: UnlimitedRetries, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+			))
+
+			_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+				context.TODO(),
+				updatePodSpec(pod, networkName, networkName+"-2"),
+				metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("is retried until it succeeds", func() {
+			Eventually(podNetworkStatus).Should(ConsistOf(
+				ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+				ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			))
+			Expect(multusClient.failedInvocations()).To(Equal(failures))
+		})
+	})
+})
+
+// flakyMultusClient fails the first delegate invocations.
+type flakyMultusClient struct {
+	multuscni.Client
+
+	lock     sync.Mutex
+	failures int
+	failed   int
+}
+
+func newFlakyMultusClient(failures int, client multuscni.Client) *flakyMultusClient {
+	return &flakyMultusClient{Client: client, failures: failures}
+}
+
+func (c *flakyMultusClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.failed < c.failures {
+		c.failed++
+		return nil, errors.New("boom")
+	}
+	return c.Client.InvokeDelegate(ctx, req)
+}
+
+func (c *flakyMultusClient) failedInvocations() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.failed
+}
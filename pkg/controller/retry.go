@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// UnlimitedRetries retries the failed reconciliations until they succeed, or the pod is gone.
+const UnlimitedRetries = -1
+
+const (
+	defaultRetryBaseDelay         = 100 * time.Millisecond
+	defaultNotFoundRetryBaseDelay = time.Second
+	defaultRetryMaxDelay          = 5 * time.Minute
+	defaultPermanentMaxRetries    = 2
+)
+
+// RetryPolicy defines how the reconciliations failing with a given class of errors are retried: up to
+// MaxRetries times - or forever, when UnlimitedRetries - with an exponential backoff from BaseDelay
+// up to MaxDelay.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy of the given class of errors, when not configured.
+func DefaultRetryPolicy(class ErrorClass) RetryPolicy {
+	switch class {
+	case ErrorClassNotFound:
+		return RetryPolicy{MaxRetries: UnlimitedRetries, BaseDelay: defaultNotFoundRetryBaseDelay, MaxDelay: defaultRetryMaxDelay}
+	case ErrorClassPermanent:
+		return RetryPolicy{MaxRetries: defaultPermanentMaxRetries, BaseDelay: defaultRetryBaseDelay, MaxDelay: defaultRetryMaxDelay}
+	default:
+		return RetryPolicy{MaxRetries: UnlimitedRetries, BaseDelay: defaultRetryBaseDelay, MaxDelay: defaultRetryMaxDelay}
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the given class of errors.
+func WithRetryPolicy(class ErrorClass, policy RetryPolicy) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.retryPolicies[class] = policy
+	}
+}
+
+func defaultRetryPolicies() map[ErrorClass]RetryPolicy {
+	retryPolicies := map[ErrorClass]RetryPolicy{}
+	for _, class := range ErrorClasses {
+		retryPolicies[class] = DefaultRetryPolicy(class)
+	}
+	return retryPolicies
+}
+
+func newRetryRateLimiters(retryPolicies map[ErrorClass]RetryPolicy) map[ErrorClass]workqueue.RateLimiter {
+	const (
+		overallQPS   = 10
+		overallBurst = 100
+	)
+	rateLimiters := map[ErrorClass]workqueue.RateLimiter{}
+	for class, policy := range retryPolicies {
+		rateLimiters[class] = workqueue.NewMaxOfRateLimiter(
+			workqueue.NewItemExponentialFailureRateLimiter(policy.BaseDelay, policy.MaxDelay),
+			&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(overallQPS), overallBurst)},
+		)
+	}
+	return rateLimiters
+}
+
+// shouldRetry tells whether the pod reconciliation is to be retried, per the policy of the error class.
+func (pnc *PodNetworksController) shouldRetry(namespacedPodName string, err error) bool {
+	class := errorClass(err)
+	policy := pnc.retryPolicies[class]
+	return policy.MaxRetries == UnlimitedRetries || pnc.rateLimiters[class].NumRequeues(namespacedPodName) < policy.MaxRetries
+}
+
+// requeue re-queues the pod once the backoff of the error class expires.
+func (pnc *PodNetworksController) requeue(ctx context.Context, namespacedPodName string, err error) {
+	if pnc.numRequeues(namespacedPodName) == 0 {
+		pnc.metrics.BackoffStarted()
+	}
+	class := errorClass(err)
+	rateLimiter := pnc.rateLimiters[class]
+	delay := rateLimiter.When(namespacedPodName)
+	pnc.metrics.ObserveRetryBackoff(string(class), delay)
+	klog.FromContext(ctx).Error(
+		err,
+		"re-queued the request",
+		"delay", delay,
+		"errorClass", class,
+		"attempt", rateLimiter.NumRequeues(namespacedPodName),
+	)
+	pnc.workqueue.AddAfter(namespacedPodName, delay)
+}
+
+// forget resets the backoffs of the pod.
+func (pnc *PodNetworksController) forget(namespacedPodName string) {
+	if pnc.numRequeues(namespacedPodName) > 0 {
+		pnc.metrics.BackoffEnded()
+	}
+	for _, rateLimiter := range pnc.rateLimiters {
+		rateLimiter.Forget(namespacedPodName)
+	}
+	pnc.workqueue.Forget(namespacedPodName)
+}
+
+func (pnc *PodNetworksController) numRequeues(namespacedPodName string) int {
+	requeues := 0
+	for _, rateLimiter := range pnc.rateLimiters {
+		requeues += rateLimiter.NumRequeues(namespacedPodName)
+	}
+	return requeues
+}
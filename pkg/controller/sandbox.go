@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+const podsByUIDIndex = "byUID"
+
+// sandboxChangeNotifier is implemented by the container runtimes notifying the pods whose sandbox
+// changed - e.g. the CRI sandbox cache.
+type sandboxChangeNotifier interface {
+	OnSandboxChange(handler func(podUID string))
+}
+
+// handleSandboxChange re-queues the pod whose sandbox changed, for its dynamic attachments to be
+// plugged into the new sandbox.
+func (pnc *PodNetworksController) handleSandboxChange(podUID string) {
+	pods, err := pnc.podsInformer.GetIndexer().ByIndex(podsByUIDIndex, podUID)
+	if err != nil {
+		klog.ErrorS(err, "failed to look up the pod", "podUID", podUID)
+		return
+	}
+	for _, obj := range pods {
+		pod, isPod := obj.(*corev1.Pod)
+		if !isPod || pod.Spec.HostNetwork {
+			continue
+		}
+		klog.InfoS("the pod sandbox changed", "pod", klog.KObj(pod))
+		pnc.workqueue.Add(annotations.NamespacedName(pod.GetNamespace(), pod.GetName()))
+	}
+}
+
+// podsByUID indexes the pods by UID, as the container runtime identifies them.
+func podsByUID(obj interface{}) ([]string, error) {
+	pod, isPod := obj.(*corev1.Pod)
+	if !isPod {
+		return nil, nil
+	}
+	return []string{string(pod.GetUID())}, nil
+}
+
+// previousSandboxAttachments returns the dynamic attachments plugged into another sandbox than the
+// current one, split between those whose interface is found in the network namespace of the current
+// sandbox - thus still plugged - and those gone along with the previous sandbox, or all gone when the
+// network namespace cannot be entered. The attachments recorded without their sandbox ID are assumed
+// to be current, and the outdated cached pods are skipped: the attachments they record may have been
+// plugged into the current sandbox already.
+func (pnc *PodNetworksController) previousSandboxAttachments(
+	ctx context.Context,
+	pod *corev1.Pod,
+	dynamicAttachments []annotations.DynamicAttachment,
+	netnsPath string,
+	podSandboxID string,
+) ([]annotations.DynamicAttachment, []nadv1.NetworkSelectionElement, error) {
+	var previousAttachments []annotations.DynamicAttachment
+	for i := range dynamicAttachments {
+		if dynamicAttachments[i].SandboxID != "" && dynamicAttachments[i].SandboxID != podSandboxID {
+			previousAttachments = append(previousAttachments, dynamicAttachments[i])
+		}
+	}
+	if len(previousAttachments) == 0 {
+		return nil, nil, nil
+	}
+	isCurrent, err := pnc.isCachedPodCurrent(ctx, pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isCurrent {
+		klog.FromContext(ctx).V(logging.Debug).Info("pod is outdated in the cache: skipping the attachments of the previous sandbox")
+		return nil, nil, nil
+	}
+
+	// when the network namespace cannot be entered, the attachments are deemed gone: plugging them anew
+	// fails at worst, while skipping them would leave them unplugged for good
+	interfaces, err := pnc.interfaceLister(netnsPath)
+	if err != nil {
+		klog.FromContext(ctx).Error(err, "failed to list the interfaces of the pod sandbox: re-plugging the attachments of the previous sandbox")
+	}
+	pluggedInterfaces := map[string]struct{}{}
+	for _, iface := range interfaces {
+		pluggedInterfaces[iface.Name] = struct{}{}
+	}
+
+	var pluggedAttachments []annotations.DynamicAttachment
+	var goneAttachments []nadv1.NetworkSelectionElement
+	for i := range previousAttachments {
+		if _, isPlugged := pluggedInterfaces[previousAttachments[i].NetworkSelectionElement.InterfaceRequest]; isPlugged {
+			pluggedAttachments = append(pluggedAttachments, previousAttachments[i])
+			continue
+		}
+		goneAttachments = append(goneAttachments, previousAttachments[i].NetworkSelectionElement)
+	}
+	return pluggedAttachments, goneAttachments, nil
+}
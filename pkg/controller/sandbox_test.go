@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The pod sandbox recreation", func() {
+	const (
+		cniVersion      = "0.3.0"
+		namespace       = "default"
+		networkName     = "tiny-net"
+		podName         = "tiny-winy-pod"
+		podUID          = "abc-def"
+		newPodSandboxID = "5678"
+	)
+
+	var (
+		k8sClient    *fake.Clientset
+		multusClient *blockingMultusClient
+		runtime      *fakecri.Runtime
+		// the delegates invoked before the sandbox recreation
+		initialDelegates int
+	)
+
+	podDynamicAttachments := func() ([]annotations.DynamicAttachment, error) {
+		pod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return annotations.PodDynamicAttachments(pod)
+	}
+
+	// the new sandbox only features the interfaces plugged by multus, while the previous one features
+	// the dynamic attachment as well, once plugged
+	sandboxInterfaces := func(netnsPath string) ([]net.Interface, error) {
+		interfaces := []net.Interface{
+			{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+			{Name: "net0", Flags: net.FlagUp},
+		}
+		if netnsPath == newPodSandboxID || len(multusClient.invokedDelegates()) == 0 {
+			return interfaces, nil
+		}
+		return append(interfaces, net.Interface{Name: "net1", Flags: net.FlagUp}), nil
+	}
+
+	startController := func(opts ...Option) *dummyPodController {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		k8sClient = fake.NewSimpleClientset(pod)
+		runtime = fakecri.NewFakeRuntime(*pod)
+		multusClient = newBlockingMultusClient("", fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net1", "")))
+		podController, err := newDummyPodController(
+			k8sClient,
+			nadClient,
+			stopChannel,
+			record.NewFakeRecorder(maxEvents),
+			runtime,
+			multusClient,
+			opts...)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSandboxID, err := runtime.PodSandboxID(context.TODO(), podUID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pod, networkName, networkName+"-2"),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(podDynamicAttachments).Should(ConsistOf(HaveField("SandboxID", podSandboxID)))
+		// the reconciliations of the outdated cached pod are over once the recorded attachment reaches the cache
+		Eventually(func() ([]annotations.DynamicAttachment, error) {
+			cachedPod, err := podController.podsLister.Pods(namespace).Get(podName)
+			if err != nil {
+				return nil, err
+			}
+			return annotations.PodDynamicAttachments(cachedPod)
+		}).Should(ConsistOf(HaveField("SandboxID", podSandboxID)))
+		initialDelegates = len(multusClient.invokedDelegates())
+		return podController
+	}
+
+	recreationDelegates := func() []string {
+		return multusClient.invokedDelegates()[initialDelegates:]
+	}
+
+	podNetworkStatus := func() ([]nad.NetworkStatus, error) {
+		updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return annotations.PodDynamicNetworkStatus(updatedPod)
+	}
+
+	When("the pod sandbox is recreated", func() {
+		BeforeEach(func() {
+			startController(withInterfaceLister(sandboxInterfaces))
+		})
+
+		It("re-plugs the dynamic attachments into the new sandbox", func() {
+			runtime.RecreateSandbox(podUID, newPodSandboxID)
+
+			Eventually(recreationDelegates).Should(Equal([]string{"ADD net1"}))
+			Eventually(podDynamicAttachments).Should(ConsistOf(HaveField("SandboxID", newPodSandboxID)))
+			Eventually(podNetworkStatus).Should(ConsistOf(
+				ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+				ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			))
+		})
+	})
+
+	When("the pod is audited as its sandbox is recreated", func() {
+		var podController *dummyPodController
+
+		BeforeEach(func() {
+			podController = startController(WithDriftAudit(time.Hour, true), withInterfaceLister(sandboxInterfaces))
+		})
+
+		It("audits the network-status cleared of the previous sandbox attachments", func() {
+			podController.pendingAudits.Store(annotations.NamespacedName(namespace, podName), struct{}{})
+			runtime.RecreateSandbox(podUID, newPodSandboxID)
+
+			Eventually(recreationDelegates).Should(Equal([]string{"ADD net1"}))
+			Consistently(recreationDelegates).WithTimeout(time.Second).Should(HaveLen(1))
+			Eventually(podDynamicAttachments).Should(ConsistOf(HaveField("SandboxID", newPodSandboxID)))
+			Eventually(podNetworkStatus).Should(ConsistOf(
+				ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+				ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			))
+		})
+	})
+
+	When("the dynamic attachments are found in the recreated sandbox", func() {
+		BeforeEach(func() {
+			sandboxInterfaces := func(string) ([]net.Interface, error) {
+				return []net.Interface{
+					{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+					{Name: "net0", Flags: net.FlagUp},
+					{Name: "net1", Flags: net.FlagUp},
+				}, nil
+			}
+			startController(withInterfaceLister(sandboxInterfaces))
+		})
+
+		It("records them with the new sandbox, without plugging them anew", func() {
+			runtime.RecreateSandbox(podUID, newPodSandboxID)
+
+			Eventually(podDynamicAttachments).Should(ConsistOf(HaveField("SandboxID", newPodSandboxID)))
+			Consistently(recreationDelegates).WithTimeout(time.Second).Should(BeEmpty())
+			Expect(podNetworkStatus()).To(ConsistOf(
+				ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+				ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			))
+		})
+	})
+
+	When("the network namespace of the recreated sandbox cannot be entered", func() {
+		BeforeEach(func() {
+			sandboxInterfaces := func(string) ([]net.Interface, error) {
+				return nil, errors.New("permission denied")
+			}
+			startController(withInterfaceLister(sandboxInterfaces))
+		})
+
+		It("re-plugs the dynamic attachments into the new sandbox", func() {
+			runtime.RecreateSandbox(podUID, newPodSandboxID)
+
+			Eventually(recreationDelegates).Should(Equal([]string{"ADD net1"}))
+			Eventually(podDynamicAttachments).Should(ConsistOf(HaveField("SandboxID", newPodSandboxID)))
+		})
+	})
+})
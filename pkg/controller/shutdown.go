@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// for how long the aborted reconciliations are waited for to record the operations they performed
+const abortGracePeriod = 5 * time.Second
+
+// errShuttingDown is returned when the reconciliation of a pod is aborted because the
+// shutdown grace period expired.
+var errShuttingDown = errors.New("the controller is shutting down")
+
+// WithShutdownGracePeriod sets for how long the in-flight reconciliations are waited for once
+// the controller is stopped; past it, their remaining CNI operations are aborted.
+func WithShutdownGracePeriod(gracePeriod time.Duration) Option {
+	return func(pnc *PodNetworksController) {
+		if gracePeriod > 0 {
+			pnc.shutdownGracePeriod = gracePeriod
+		}
+	}
+}
+
+// drain waits for the in-flight reconciliations to complete - no new ones are started. Once the
+// shutdown grace period expires, their in-flight and remaining CNI operations are aborted, and the
+// ones already performed are recorded in the pods network-status.
+func (pnc *PodNetworksController) drain() {
+	drained := make(chan struct{})
+	go func() {
+		pnc.workqueue.ShutDownWithDrain()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		klog.InfoS("drained the in-flight reconciliations")
+		return
+	case <-time.After(pnc.shutdownGracePeriod):
+		klog.InfoS(
+			"the in-flight reconciliations did not complete within the grace period; aborting them",
+			"gracePeriod", pnc.shutdownGracePeriod,
+		)
+		pnc.abort()
+	}
+
+	select {
+	case <-drained:
+		klog.InfoS("aborted the in-flight reconciliations")
+	case <-time.After(abortGracePeriod):
+		klog.ErrorS(nil, "the aborted reconciliations did not complete within the grace period", "gracePeriod", abortGracePeriod)
+		pnc.workqueue.ShutDown()
+	}
+}
+
+func (pnc *PodNetworksController) isAborted() bool {
+	return pnc.abortCtx.Err() != nil
+}
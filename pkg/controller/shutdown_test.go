@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The dynamic attachment controller shutdown", func() {
+	const (
+		cniVersion   = "0.3.0"
+		namespace    = "default"
+		networkName  = "tiny-net"
+		podName      = "tiny-winy-pod"
+		podUID       = "abc-def"
+		blockedIface = "net2"
+	)
+
+	var (
+		k8sClient    *fake.Clientset
+		multusClient *blockingMultusClient
+		stopChannel  chan struct{}
+		stop         func()
+	)
+
+	podNetworkStatus := func() ([]nad.NetworkStatus, error) {
+		updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return annotations.PodDynamicNetworkStatus(updatedPod)
+	}
+
+	startController := func(gracePeriod time.Duration) {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)),
+			netAttachDef(networkName+"-3", namespace, dummyNetSpec(networkName+"-3", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sClient = fake.NewSimpleClientset(pod)
+		const maxEvents = 5
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(*pod),
+				multusClient,
+				WithShutdownGracePeriod(gracePeriod),
+			)).NotTo(BeNil())
+
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pod, networkName, networkName+"-2", networkName+"-3"),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(multusClient.blocked).Should(BeClosed())
+	}
+
+	BeforeEach(func() {
+		multusClient = newBlockingMultusClient(
+			blockedIface,
+			fakemultusclient.NewFakeClient(
+				networkConfig(multuscni.CmdAdd, "net1", ""),
+				networkConfig(multuscni.CmdAdd, "net2", ""),
+			),
+		)
+		stopChannel = make(chan struct{})
+		var once sync.Once
+		stop = func() { once.Do(func() { close(stopChannel) }) }
+		DeferCleanup(func() {
+			multusClient.release()
+			stop()
+		})
+	})
+
+	It("completes the in-flight reconciliations within the grace period", func() {
+		const gracePeriod = 5 * time.Second
+		startController(gracePeriod)
+
+		stop()
+		multusClient.release()
+
+		Eventually(podNetworkStatus).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-3", "net2", ""),
+		))
+	})
+
+	It("cancels the in-flight CNI operations once the grace period expires, recording the performed ones", func() {
+		const gracePeriod = 100 * time.Millisecond
+		startController(gracePeriod)
+
+		stop()
+
+		Eventually(podNetworkStatus).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+		))
+		Consistently(podNetworkStatus).WithTimeout(time.Second).Should(HaveLen(2))
+		Expect(multusClient.invokedDelegates()).To(ConsistOf("ADD net1", "ADD net2"))
+	})
+})
+
+// blockingMultusClient blocks the delegate invocations for the given interface until released.
+type blockingMultusClient struct {
+	multuscni.Client
+	blockedIface string
+	blocked      chan struct{}
+	released     chan struct{}
+	releaseOnce  sync.Once
+	blockOnce    sync.Once
+
+	lock        sync.Mutex
+	invocations []string
+}
+
+func newBlockingMultusClient(blockedIface string, client multuscni.Client) *blockingMultusClient {
+	return &blockingMultusClient{
+		Client:       client,
+		blockedIface: blockedIface,
+		blocked:      make(chan struct{}),
+		released:     make(chan struct{}),
+	}
+}
+
+func (c *blockingMultusClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	ifaceName := req.Env["CNI_IFNAME"]
+	c.lock.Lock()
+	c.invocations = append(c.invocations, fmt.Sprintf("%s %s", req.Env["CNI_COMMAND"], ifaceName))
+	c.lock.Unlock()
+
+	if ifaceName == c.blockedIface {
+		c.blockOnce.Do(func() { close(c.blocked) })
+		select {
+		case <-c.released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return c.Client.InvokeDelegate(ctx, req)
+}
+
+func (c *blockingMultusClient) release() {
+	c.releaseOnce.Do(func() { close(c.released) })
+}
+
+// invokedDelegates returns the invoked delegates, as "<command> <interface name>".
+func (c *blockingMultusClient) invokedDelegates() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.invocations...)
+}
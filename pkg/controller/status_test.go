@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The pod status writes", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var k8sClient *fake.Clientset
+
+	// concurrentWrite adds an interface to the pod network-status behind the controller's back, as
+	// another writer - e.g. multus - would.
+	concurrentWrite := func() error {
+		podsResource := corev1.SchemeGroupVersion.WithResource("pods")
+		obj, err := k8sClient.Tracker().Get(podsResource, namespace, podName)
+		if err != nil {
+			return err
+		}
+		pod := obj.(*corev1.Pod).DeepCopy()
+		networkStatus, err := annotations.PodDynamicNetworkStatus(pod)
+		if err != nil {
+			return err
+		}
+		serializedStatus, err := json.Marshal(append(networkStatus, ifaceStatusForDefaultNamespace("other-net", "net9", "")))
+		if err != nil {
+			return err
+		}
+		pod.Annotations[nad.NetworkStatusAnnot] = string(serializedStatus)
+		return k8sClient.Tracker().Update(podsResource, pod, namespace)
+	}
+
+	BeforeEach(func() {
+		pod := podSpec(podName, namespace, podUID, networkName)
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		k8sClient = fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(*pod),
+				fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net1", "")),
+			)).NotTo(BeNil())
+
+		// the first status write recording the added attachment conflicts with a concurrent one
+		conflicted := false
+		k8sClient.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			patch := string(action.(k8stesting.PatchAction).GetPatch())
+			if conflicted || !strings.Contains(patch, networkName+"-2") {
+				return false, nil, nil
+			}
+			conflicted = true
+			if err := concurrentWrite(); err != nil {
+				return true, nil, err
+			}
+			return true, nil, apierrors.NewConflict(corev1.Resource("pods"), podName, nil)
+		})
+
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pod, networkName, networkName+"-2"),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("recomputes the status out of the refetched pod on conflict, preserving the concurrent changes", func() {
+		Eventually(func() ([]nad.NetworkStatus, error) {
+			updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return annotations.PodDynamicNetworkStatus(updatedPod)
+		}).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace("other-net", "net9", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+		))
+	})
+})
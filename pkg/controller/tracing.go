@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/tracing"
+)
+
+// WithTracerProvider sets the provider of the tracer the reconciliations are traced with; it defaults
+// to the global one, which discards the spans unless tracing is set up.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(pnc *PodNetworksController) {
+		pnc.tracer = tracerProvider.Tracer(tracing.TracerName)
+	}
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracing.TracerName)
+}
+
+// netAttachDef gets the network-attachment-definition from the informer cache, within its own span.
+func (pnc *PodNetworksController) netAttachDef(
+	ctx context.Context,
+	namespace, name string,
+) (*nadv1.NetworkAttachmentDefinition, error) {
+	_, span := pnc.tracer.Start(
+		ctx,
+		"GetNetworkAttachmentDefinition",
+		trace.WithAttributes(tracing.NetAttachDefNsKey.String(namespace), tracing.NetAttachDefKey.String(name)),
+	)
+	netAttachDef, err := pnc.netAttachDefLister.NetworkAttachmentDefinitions(namespace).Get(name)
+	tracing.End(span, err)
+	return netAttachDef, err
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The reconciliations tracing", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+	)
+
+	var spanRecorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		pod := podSpec(podName, namespace, podUID)
+		nadClient, err := newFakeNetAttachDefClient(netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		spanRecorder = tracetest.NewSpanRecorder()
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 5
+		k8sClient := fake.NewSimpleClientset(pod)
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(*pod),
+				fakemultusclient.NewFakeClient(networkConfig(multuscni.CmdAdd, "net0", "")),
+				WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))),
+			)).NotTo(BeNil())
+
+		_, err = k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pod, networkName),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("traces each step of the hotplug as a child span of the reconciliation", func() {
+		reconcileSpan := func() sdktrace.ReadOnlySpan {
+			for _, span := range spanRecorder.Ended() {
+				if span.Name() == "Reconcile" && slices.Contains(childSpans(spanRecorder, span), "InvokeDelegate") {
+					return span
+				}
+			}
+			return nil
+		}
+		Eventually(reconcileSpan).ShouldNot(BeNil())
+		Expect(childSpans(spanRecorder, reconcileSpan())).To(Equal([]string{
+			"NetworkNamespace",
+			"PodSandboxID",
+			"GetNetworkAttachmentDefinition",
+			"InvokeDelegate",
+			"UpdatePodStatus",
+		}))
+	})
+})
+
+// childSpans returns the names of the ended child spans of the span, in the order they started.
+func childSpans(spanRecorder *tracetest.SpanRecorder, parent sdktrace.ReadOnlySpan) []string {
+	var children []string
+	for _, span := range spanRecorder.Started() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			children = append(children, span.Name())
+		}
+	}
+	return children
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+
+	nad "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	fakecri "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni"
+	fakemultusclient "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/multuscni/fake"
+)
+
+var _ = Describe("The parallel workers", func() {
+	const (
+		cniVersion  = "0.3.0"
+		namespace   = "default"
+		networkName = "tiny-net"
+		podName     = "tiny-winy-pod"
+		podUID      = "abc-def"
+		otherPod    = "tiny-winy-pod-2"
+		otherPodUID = "ghi-jkl"
+		workers     = 3
+	)
+
+	var (
+		k8sClient    *fake.Clientset
+		multusClient *concurrencyTrackingMultusClient
+		pods         map[string]*corev1.Pod
+	)
+
+	updatePodNetworks := func(name string, networks ...string) {
+		_, err := k8sClient.CoreV1().Pods(namespace).UpdateStatus(
+			context.TODO(),
+			updatePodSpec(pods[name], networks...),
+			metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	podNetworkStatus := func(name string) func() ([]nad.NetworkStatus, error) {
+		return func() ([]nad.NetworkStatus, error) {
+			updatedPod, err := k8sClient.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return annotations.PodDynamicNetworkStatus(updatedPod)
+		}
+	}
+
+	BeforeEach(func() {
+		pods = map[string]*corev1.Pod{
+			podName:  podSpec(podName, namespace, podUID, networkName),
+			otherPod: podSpec(otherPod, namespace, otherPodUID, networkName),
+		}
+		nadClient, err := newFakeNetAttachDefClient(
+			netAttachDef(networkName, namespace, dummyNetSpec(networkName, cniVersion)),
+			netAttachDef(networkName+"-2", namespace, dummyNetSpec(networkName+"-2", cniVersion)),
+			netAttachDef(networkName+"-3", namespace, dummyNetSpec(networkName+"-3", cniVersion)))
+		Expect(err).NotTo(HaveOccurred())
+
+		multusClient = newConcurrencyTrackingMultusClient(
+			fakemultusclient.NewFakeClient(
+				networkConfig(multuscni.CmdAdd, "net1", ""),
+				networkConfig(multuscni.CmdAdd, "net2", ""),
+			),
+		)
+		DeferCleanup(multusClient.release)
+
+		stopChannel := make(chan struct{})
+		DeferCleanup(func() { close(stopChannel) })
+		const maxEvents = 10
+		k8sClient = fake.NewSimpleClientset(pods[podName], pods[otherPod])
+		Expect(
+			newDummyPodController(
+				k8sClient,
+				nadClient,
+				stopChannel,
+				record.NewFakeRecorder(maxEvents),
+				fakecri.NewFakeRuntime(*pods[podName], *pods[otherPod]),
+				multusClient,
+				WithWorkers(workers),
+			)).NotTo(BeNil())
+	})
+
+	It("reconciles different pods in parallel, but never the same pod concurrently", func() {
+		updatePodNetworks(podName, networkName, networkName+"-2")
+		updatePodNetworks(otherPod, networkName, networkName+"-2")
+		Eventually(multusClient.inFlightInvocations).Should(Equal(2))
+
+		// the pod being reconciled is updated anew: the idle worker must not pick it up meanwhile
+		updatePodNetworks(podName, networkName, networkName+"-2", networkName+"-3")
+		Consistently(multusClient.inFlightInvocations).WithTimeout(time.Second).Should(Equal(2))
+
+		multusClient.release()
+		Eventually(podNetworkStatus(podName)).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-3", "net2", ""),
+		))
+		Eventually(podNetworkStatus(otherPod)).Should(ConsistOf(
+			ifaceStatusForDefaultNamespace(networkName, "net0", ""),
+			ifaceStatusForDefaultNamespace(networkName+"-2", "net1", ""),
+		))
+		Expect(multusClient.maxInFlightInvocationsPerPod()).To(Equal(1))
+	})
+})
+
+// concurrencyTrackingMultusClient blocks the delegate invocations until released, tracking how many
+// of them are in flight - overall, and per pod network namespace.
+type concurrencyTrackingMultusClient struct {
+	multuscni.Client
+	released    chan struct{}
+	releaseOnce sync.Once
+
+	lock              sync.Mutex
+	inFlight          int
+	inFlightPerPod    map[string]int
+	maxInFlightPerPod int
+}
+
+func newConcurrencyTrackingMultusClient(client multuscni.Client) *concurrencyTrackingMultusClient {
+	return &concurrencyTrackingMultusClient{
+		Client:         client,
+		released:       make(chan struct{}),
+		inFlightPerPod: map[string]int{},
+	}
+}
+
+func (c *concurrencyTrackingMultusClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	netnsPath := req.Env["CNI_NETNS"]
+	c.lock.Lock()
+	c.inFlight++
+	c.inFlightPerPod[netnsPath]++
+	c.maxInFlightPerPod = max(c.maxInFlightPerPod, c.inFlightPerPod[netnsPath])
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		c.inFlight--
+		c.inFlightPerPod[netnsPath]--
+		c.lock.Unlock()
+	}()
+
+	select {
+	case <-c.released:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return c.Client.InvokeDelegate(ctx, req)
+}
+
+func (c *concurrencyTrackingMultusClient) inFlightInvocations() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.inFlight
+}
+
+func (c *concurrencyTrackingMultusClient) maxInFlightInvocationsPerPod() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.maxInFlightPerPod
+}
+
+func (c *concurrencyTrackingMultusClient) release() {
+	c.releaseOnce.Do(func() { close(c.released) })
+}
@@ -0,0 +1,306 @@
+package cri
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cri "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubelet/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+// eventsRetryPeriod is how long the cache waits before re-opening a broken container events stream
+const eventsRetryPeriod = 5 * time.Second
+
+// SandboxCache tracks the pod sandboxes of the CRI runtime, so that the per-pod lookups are served from
+// memory. It is populated by listing the sandboxes, then kept current by the container events stream of
+// the runtime; the sandboxes are listed anew periodically, which is all there is for the runtimes not
+// streaming the events.
+type SandboxCache struct {
+	runtime      *Runtime
+	resyncPeriod time.Duration
+
+	lock      sync.RWMutex
+	sandboxes map[string]sandbox // Key: podUID
+	populated bool
+	handlers  []func(podUID string)
+}
+
+// sandbox is the current sandbox of a pod
+type sandbox struct {
+	id        string
+	createdAt int64
+	ready     bool
+	// netnsPath is lazily fetched from the sandbox status, then cached
+	netnsPath string
+}
+
+// NewSandboxCache returns a cache of the pod sandboxes of the runtime, listed anew every resync period.
+func NewSandboxCache(runtime *Runtime, resyncPeriod time.Duration) *SandboxCache {
+	return &SandboxCache{
+		runtime:      runtime,
+		resyncPeriod: resyncPeriod,
+		sandboxes:    map[string]sandbox{},
+	}
+}
+
+// OnSandboxChange registers a handler notified of the pods whose sandbox changed - e.g. was recreated.
+// The handlers must be registered before the cache is started.
+func (c *SandboxCache) OnSandboxChange(handler func(podUID string)) {
+	c.handlers = append(c.handlers, handler)
+}
+
+// Start populates the cache, then keeps it current - in the background - until the context is done.
+func (c *SandboxCache) Start(ctx context.Context) {
+	c.resync(ctx)
+	go c.pollSandboxes(ctx)
+	go c.watchEvents(ctx)
+}
+
+// PodSandboxID returns the PodSandboxID of the given pod. The pods missing from the cache - e.g. whose
+// sandbox was created after the last update - are looked up in the runtime.
+func (c *SandboxCache) PodSandboxID(ctx context.Context, podUID string) (string, error) {
+	podSandbox, err := c.podSandbox(ctx, podUID)
+	if err != nil {
+		return "", err
+	}
+	return podSandbox.id, nil
+}
+
+// NetworkNamespace returns the network namespace of the given pod.
+func (c *SandboxCache) NetworkNamespace(ctx context.Context, podUID string) (string, error) {
+	podSandbox, err := c.podSandbox(ctx, podUID)
+	if err != nil {
+		return "", err
+	}
+	if podSandbox.netnsPath != "" {
+		return podSandbox.netnsPath, nil
+	}
+
+	netnsPath, err := c.runtime.sandboxNetworkNamespace(ctx, podSandbox.id)
+	if err != nil {
+		return "", err
+	}
+	c.lock.Lock()
+	if current, exists := c.sandboxes[podUID]; exists && current.id == podSandbox.id {
+		current.netnsPath = netnsPath
+		c.sandboxes[podUID] = current
+	}
+	c.lock.Unlock()
+	return netnsPath, nil
+}
+
+// Healthz checks the CRI runtime answers requests, and reports itself as ready
+func (c *SandboxCache) Healthz(ctx context.Context) error {
+	return c.runtime.Healthz(ctx)
+}
+
+func (c *SandboxCache) podSandbox(ctx context.Context, podUID string) (sandbox, error) {
+	c.lock.RLock()
+	podSandbox, exists := c.sandboxes[podUID]
+	c.lock.RUnlock()
+	if exists {
+		return podSandbox, nil
+	}
+
+	podSandboxes, err := c.listPodSandboxes(ctx, &cri.PodSandboxFilter{
+		LabelSelector: map[string]string{types.KubernetesPodUIDLabel: podUID},
+	})
+	if err != nil {
+		return sandbox{}, err
+	}
+	podSandbox, exists = podSandboxes[podUID]
+	if !exists {
+		return sandbox{}, fmt.Errorf("ListPodSandbox returned 0 item for pod %s", podUID)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exists := c.sandboxes[podUID]; !exists {
+		c.sandboxes[podUID] = podSandbox
+		return podSandbox, nil
+	}
+	c.observe(podUID, podSandbox)
+	return c.sandboxes[podUID], nil
+}
+
+// resync lists the sandboxes of the runtime anew, forgetting the pods no longer featuring any.
+func (c *SandboxCache) resync(ctx context.Context) {
+	podSandboxes, err := c.listPodSandboxes(ctx, nil)
+	if err != nil {
+		klog.ErrorS(err, "failed to resync the pod sandboxes cache")
+		return
+	}
+
+	var changedPods []string
+	c.lock.Lock()
+	for podUID := range c.sandboxes {
+		if _, exists := podSandboxes[podUID]; !exists {
+			delete(c.sandboxes, podUID)
+		}
+	}
+	for podUID, podSandbox := range podSandboxes {
+		if c.observe(podUID, podSandbox) {
+			changedPods = append(changedPods, podUID)
+		}
+	}
+	c.populated = true
+	klog.V(logging.Debug).InfoS("resynced the pod sandboxes cache", "sandboxes", len(c.sandboxes))
+	c.lock.Unlock()
+
+	c.notify(changedPods...)
+}
+
+// pollSandboxes lists the sandboxes anew every resync period, until the context is done.
+func (c *SandboxCache) pollSandboxes(ctx context.Context) {
+	ticker := time.NewTicker(c.resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.resync(ctx)
+		}
+	}
+}
+
+// listPodSandboxes lists the sandboxes matching the filter, indexed by pod UID. Out of the sandboxes
+// of a pod, the ready one - or else the most recent one - is its current sandbox.
+func (c *SandboxCache) listPodSandboxes(ctx context.Context, filter *cri.PodSandboxFilter) (map[string]sandbox, error) {
+	listPodSandboxResponse, err := c.runtime.Client.ListPodSandbox(ctx, &cri.ListPodSandboxRequest{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ListPodSandbox: %w", err)
+	}
+
+	podSandboxes := map[string]sandbox{}
+	for _, item := range listPodSandboxResponse.GetItems() {
+		podUID := item.GetMetadata().GetUid()
+		if podUID == "" {
+			continue
+		}
+		listed := sandbox{
+			id:        item.GetId(),
+			createdAt: item.GetCreatedAt(),
+			ready:     item.GetState() == cri.PodSandboxState_SANDBOX_READY,
+		}
+		if current, exists := podSandboxes[podUID]; !exists || supersedes(listed, current) {
+			podSandboxes[podUID] = listed
+		}
+	}
+	return podSandboxes, nil
+}
+
+// watchEvents keeps the cache current using the container events stream, re-opening it when it
+// breaks, until the context is done or the runtime turns out not to stream the events.
+func (c *SandboxCache) watchEvents(ctx context.Context) {
+	for {
+		err := c.streamEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			klog.InfoS("the CRI runtime does not stream the container events: polling the pod sandboxes", "period", c.resyncPeriod)
+			return
+		}
+		klog.ErrorS(err, "the CRI container events stream broke")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventsRetryPeriod):
+		}
+		// the events streamed meanwhile were missed
+		c.resync(ctx)
+	}
+}
+
+func (c *SandboxCache) streamEvents(ctx context.Context) error {
+	stream, err := c.runtime.Client.GetContainerEvents(ctx, &cri.GetEventsRequest{})
+	if err != nil {
+		return err
+	}
+	klog.V(logging.Debug).InfoS("watching the CRI container events")
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		c.handleEvent(event)
+	}
+}
+
+// handleEvent updates the sandbox of the pod the container event is about.
+func (c *SandboxCache) handleEvent(event *cri.ContainerEventResponse) {
+	podSandboxStatus := event.GetPodSandboxStatus()
+	podUID := podSandboxStatus.GetMetadata().GetUid()
+	if podUID == "" {
+		return
+	}
+
+	c.lock.Lock()
+	if event.GetContainerEventType() == cri.ContainerEventType_CONTAINER_DELETED_EVENT &&
+		event.GetContainerId() == podSandboxStatus.GetId() {
+		if current, exists := c.sandboxes[podUID]; exists && current.id == podSandboxStatus.GetId() {
+			delete(c.sandboxes, podUID)
+		}
+		c.lock.Unlock()
+		return
+	}
+	changed := c.observe(podUID, sandbox{
+		id:        podSandboxStatus.GetId(),
+		createdAt: podSandboxStatus.GetCreatedAt(),
+		ready:     podSandboxStatus.GetState() == cri.PodSandboxState_SANDBOX_READY,
+	})
+	c.lock.Unlock()
+
+	if changed {
+		c.notify(podUID)
+	}
+}
+
+// observe records the sandbox of the pod, unless its current one supersedes it. It tells whether the
+// sandbox of the pod changed - the sandboxes listed while populating the cache are not changes; must
+// be called with the lock held.
+func (c *SandboxCache) observe(podUID string, observed sandbox) bool {
+	current, exists := c.sandboxes[podUID]
+	switch {
+	case !exists && !c.populated:
+		c.sandboxes[podUID] = observed
+		return false
+	case !exists:
+	case current.id == observed.id:
+		observed.netnsPath = current.netnsPath
+		c.sandboxes[podUID] = observed
+		return false
+	case !supersedes(observed, current):
+		return false
+	}
+	klog.V(logging.Debug).InfoS("the pod sandbox changed", "podUID", podUID, "sandboxID", observed.id)
+	c.sandboxes[podUID] = observed
+	return true
+}
+
+func (c *SandboxCache) notify(podUIDs ...string) {
+	for _, podUID := range podUIDs {
+		for _, handler := range c.handlers {
+			handler(podUID)
+		}
+	}
+}
+
+// supersedes tells whether a sandbox is more current than another one of the same pod: a ready one
+// supersedes those which are not, then the most recent one does.
+func supersedes(candidate sandbox, current sandbox) bool {
+	if candidate.ready != current.ready {
+		return candidate.ready
+	}
+	return candidate.createdAt > current.createdAt
+}
@@ -0,0 +1,75 @@
+package cri_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri/fake"
+)
+
+var _ = Describe("CRI sandbox cache", func() {
+	const (
+		podUID       = "abc-def"
+		podSandboxID = "1234"
+		netnsPath    = "bottom-drawer"
+	)
+
+	var (
+		runtimeClient  *fake.CrioClient
+		sandboxChanges chan string
+	)
+
+	startCache := func(resyncPeriod time.Duration, opts ...fake.ClientOpt) *cri.SandboxCache {
+		runtimeClient = fake.NewFakeClient(append(opts, fake.WithCachedContainer(podUID, podSandboxID, netnsPath))...)
+		sandboxCache := cri.NewSandboxCache(&cri.Runtime{Client: runtimeClient}, resyncPeriod)
+		changes := make(chan string, 10)
+		sandboxCache.OnSandboxChange(func(podUID string) { changes <- podUID })
+		sandboxChanges = changes
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		sandboxCache.Start(ctx)
+
+		Expect(sandboxCache.PodSandboxID(context.Background(), podUID)).To(Equal(podSandboxID))
+		Expect(sandboxCache.NetworkNamespace(context.Background(), podUID)).To(Equal(netnsPath))
+		return sandboxCache
+	}
+
+	It("cannot look up the pods without sandbox", func() {
+		sandboxCache := startCache(time.Hour)
+		_, err := sandboxCache.PodSandboxID(context.Background(), "ghi-jkl")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("serves the lookups from memory until the sandboxes are listed anew", func() {
+		sandboxCache := startCache(time.Hour)
+		runtimeClient.RecreateSandbox(podUID, "5678", "top-drawer")
+		Consistently(sandboxCache.PodSandboxID).WithArguments(context.Background(), podUID).
+			WithTimeout(200 * time.Millisecond).Should(Equal(podSandboxID))
+	})
+
+	It("learns about the recreated sandboxes from the container events", func() {
+		sandboxCache := startCache(time.Hour, fake.WithContainerEvents())
+		runtimeClient.RecreateSandbox(podUID, "5678", "top-drawer")
+		Eventually(sandboxCache.PodSandboxID).WithArguments(context.Background(), podUID).Should(Equal("5678"))
+		Expect(sandboxCache.NetworkNamespace(context.Background(), podUID)).To(Equal("top-drawer"))
+	})
+
+	It("notifies the pods whose sandbox was recreated", func() {
+		startCache(time.Hour, fake.WithContainerEvents())
+		Expect(sandboxChanges).NotTo(Receive())
+		runtimeClient.RecreateSandbox(podUID, "5678", "top-drawer")
+		Eventually(sandboxChanges).Should(Receive(Equal(podUID)))
+	})
+
+	It("polls the sandboxes when the runtime does not stream the container events", func() {
+		sandboxCache := startCache(100 * time.Millisecond)
+		runtimeClient.RecreateSandbox(podUID, "5678", "top-drawer")
+		Eventually(sandboxCache.PodSandboxID).WithArguments(context.Background(), podUID).Should(Equal("5678"))
+		Expect(sandboxCache.NetworkNamespace(context.Background(), podUID)).To(Equal("top-drawer"))
+	})
+})
@@ -4,26 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/cri"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	crioruntime "k8s.io/cri-api/pkg/apis/runtime/v1"
 	"k8s.io/kubelet/pkg/types"
 )
 
 type CrioClient struct {
+	lock              *sync.Mutex
 	cachePodSandboxID map[string]string // Key: podUID, value: podSandboxID
 	cacheNetNs        map[string]string // Key: podSandboxID, value: netns
+	createdAt         map[string]int64  // Key: podSandboxID, value: creation timestamp
+	runtimeNotReady   bool
+	events            chan *crioruntime.ContainerEventResponse
 }
 
 type ClientOpt func(client *CrioClient)
 
 func NewFakeClient(opts ...ClientOpt) *CrioClient {
 	client := &CrioClient{
+		lock:              &sync.Mutex{},
 		cachePodSandboxID: map[string]string{},
 		cacheNetNs:        map[string]string{},
+		createdAt:         map[string]int64{},
 	}
 	for _, opt := range opts {
 		opt(client)
@@ -33,8 +42,51 @@ func NewFakeClient(opts ...ClientOpt) *CrioClient {
 
 func WithCachedContainer(podUID, podSandboxID string, netnsPath string) ClientOpt {
 	return func(client *CrioClient) {
-		client.cachePodSandboxID[podUID] = podSandboxID
-		client.cacheNetNs[podSandboxID] = netnsPath
+		client.addSandbox(podUID, podSandboxID, netnsPath)
+	}
+}
+
+// WithContainerEvents streams the sandbox changes as container events; without it, the client
+// reports GetContainerEvents as unimplemented.
+func WithContainerEvents() ClientOpt {
+	return func(client *CrioClient) {
+		client.events = make(chan *crioruntime.ContainerEventResponse, 10)
+	}
+}
+
+// RecreateSandbox replaces the sandbox of the pod, streaming the matching container event when
+// the container events are enabled.
+func (cc CrioClient) RecreateSandbox(podUID, podSandboxID string, netnsPath string) {
+	createdAt := cc.addSandbox(podUID, podSandboxID, netnsPath)
+	if cc.events == nil {
+		return
+	}
+	cc.events <- &crioruntime.ContainerEventResponse{
+		ContainerId:        podSandboxID,
+		ContainerEventType: crioruntime.ContainerEventType_CONTAINER_STARTED_EVENT,
+		CreatedAt:          createdAt,
+		PodSandboxStatus: &crioruntime.PodSandboxStatus{
+			Id:        podSandboxID,
+			Metadata:  &crioruntime.PodSandboxMetadata{Uid: podUID},
+			State:     crioruntime.PodSandboxState_SANDBOX_READY,
+			CreatedAt: createdAt,
+		},
+	}
+}
+
+func (cc CrioClient) addSandbox(podUID, podSandboxID string, netnsPath string) int64 {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	createdAt := int64(len(cc.createdAt) + 1)
+	cc.cachePodSandboxID[podUID] = podSandboxID
+	cc.cacheNetNs[podSandboxID] = netnsPath
+	cc.createdAt[podSandboxID] = createdAt
+	return createdAt
+}
+
+func WithRuntimeNotReady() ClientOpt {
+	return func(client *CrioClient) {
+		client.runtimeNotReady = true
 	}
 }
 
@@ -71,6 +123,8 @@ func (cc CrioClient) PodSandboxStatus(
 	podSandboxStatusRequest *crioruntime.PodSandboxStatusRequest,
 	_ ...grpc.CallOption,
 ) (*crioruntime.PodSandboxStatusResponse, error) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
 	netnsPath, exists := cc.cacheNetNs[podSandboxStatusRequest.PodSandboxId]
 	if !exists {
 		return nil, nil
@@ -92,22 +146,23 @@ func (cc CrioClient) ListPodSandbox(
 	listPodSandboxRequest *crioruntime.ListPodSandboxRequest,
 	_ ...grpc.CallOption,
 ) (*crioruntime.ListPodSandboxResponse, error) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
 	res := &crioruntime.ListPodSandboxResponse{
 		Items: []*crioruntime.PodSandbox{},
 	}
-	podUID, exists := listPodSandboxRequest.Filter.LabelSelector[types.KubernetesPodUIDLabel]
-	if !exists {
-		return res, nil
-	}
-
-	id, exists := cc.cachePodSandboxID[podUID]
-	if !exists {
-		return res, nil
+	selectedUID, isFiltered := listPodSandboxRequest.GetFilter().GetLabelSelector()[types.KubernetesPodUIDLabel]
+	for podUID, id := range cc.cachePodSandboxID {
+		if isFiltered && selectedUID != podUID {
+			continue
+		}
+		res.Items = append(res.Items, &crioruntime.PodSandbox{
+			Id:        id,
+			Metadata:  &crioruntime.PodSandboxMetadata{Uid: podUID},
+			State:     crioruntime.PodSandboxState_SANDBOX_READY,
+			CreatedAt: cc.createdAt[id],
+		})
 	}
-
-	res.Items = append(res.Items, &crioruntime.PodSandbox{
-		Id: id,
-	})
 	return res, nil
 }
 
@@ -231,12 +286,21 @@ func (CrioClient) UpdateRuntimeConfig(
 	return nil, nil
 }
 
-func (CrioClient) Status(
+func (cc CrioClient) Status(
 	context.Context,
 	*crioruntime.StatusRequest,
 	...grpc.CallOption,
 ) (*crioruntime.StatusResponse, error) {
-	return nil, nil
+	return &crioruntime.StatusResponse{
+		Status: &crioruntime.RuntimeStatus{
+			Conditions: []*crioruntime.RuntimeCondition{
+				{
+					Type:   crioruntime.RuntimeReady,
+					Status: !cc.runtimeNotReady,
+				},
+			},
+		},
+	}, nil
 }
 
 func (cc CrioClient) PodSandboxStats(
@@ -264,11 +328,30 @@ func (cc CrioClient) CheckpointContainer(
 }
 
 func (cc CrioClient) GetContainerEvents(
-	context.Context,
-	*crioruntime.GetEventsRequest,
-	...grpc.CallOption,
+	ctx context.Context,
+	_ *crioruntime.GetEventsRequest,
+	_ ...grpc.CallOption,
 ) (crioruntime.RuntimeService_GetContainerEventsClient, error) {
-	return nil, nil
+	if cc.events == nil {
+		return nil, status.Error(codes.Unimplemented, "GetContainerEvents is not implemented")
+	}
+	return &containerEventsStream{ctx: ctx, events: cc.events}, nil
+}
+
+// containerEventsStream streams the container events until its context is done
+type containerEventsStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	events chan *crioruntime.ContainerEventResponse
+}
+
+func (s *containerEventsStream) Recv() (*crioruntime.ContainerEventResponse, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case event := <-s.events:
+		return event, nil
+	}
 }
 
 func (cc CrioClient) ListMetricDescriptors(
@@ -5,12 +5,15 @@ import (
 	"crypto/md5" // #nosec
 	"encoding/hex"
 	"fmt"
+	"sync"
 
 	v1 "k8s.io/api/core/v1"
 )
 
 type Runtime struct {
-	cache map[string]string
+	lock     sync.Mutex
+	cache    map[string]string
+	handlers []func(podUID string)
 }
 
 func NewFakeRuntime(pods ...v1.Pod) *Runtime {
@@ -24,6 +27,8 @@ func NewFakeRuntime(pods ...v1.Pod) *Runtime {
 }
 
 func (r *Runtime) NetworkNamespace(_ context.Context, podUID string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	if netnsName, wasFound := r.cache[podUID]; wasFound {
 		return netnsName, nil
 	}
@@ -31,8 +36,25 @@ func (r *Runtime) NetworkNamespace(_ context.Context, podUID string) (string, er
 }
 
 func (r *Runtime) PodSandboxID(_ context.Context, podUID string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	if netnsName, wasFound := r.cache[podUID]; wasFound {
 		return netnsName, nil
 	}
 	return "", fmt.Errorf("could not find a PodSandboxID for pod: %s", podUID)
 }
+
+// OnSandboxChange registers a handler notified of the pods whose sandbox is recreated
+func (r *Runtime) OnSandboxChange(handler func(podUID string)) {
+	r.handlers = append(r.handlers, handler)
+}
+
+// RecreateSandbox replaces the sandbox - and network namespace - of the pod, notifying the handlers
+func (r *Runtime) RecreateSandbox(podUID string, podSandboxID string) {
+	r.lock.Lock()
+	r.cache[podUID] = podSandboxID
+	r.lock.Unlock()
+	for _, handler := range r.handlers {
+		handler(podUID)
+	}
+}
@@ -41,7 +41,12 @@ func (r *Runtime) NetworkNamespace(ctx context.Context, podUID string) (string,
 	if err != nil {
 		return "", err
 	}
+	return r.sandboxNetworkNamespace(ctx, podSandboxID)
+}
 
+// sandboxNetworkNamespace returns the network namespace of the given PodSandboxID, as found in the
+// runtime spec of the sandbox.
+func (r *Runtime) sandboxNetworkNamespace(ctx context.Context, podSandboxID string) (string, error) {
 	podSandboxStatus, err := r.Client.PodSandboxStatus(ctx, &cri.PodSandboxStatusRequest{
 		PodSandboxId: podSandboxID,
 		Verbose:      true,
@@ -99,6 +104,25 @@ func (r *Runtime) PodSandboxID(ctx context.Context, podUID string) (string, erro
 	return ListPodSandboxRequest.Items[0].Id, nil
 }
 
+// Healthz checks the CRI runtime answers requests, and reports itself as ready
+func (r *Runtime) Healthz(ctx context.Context) error {
+	if _, err := r.Client.Version(ctx, &cri.VersionRequest{}); err != nil {
+		return fmt.Errorf("failed to query the CRI version: %w", err)
+	}
+
+	runtimeStatus, err := r.Client.Status(ctx, &cri.StatusRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to query the CRI status: %w", err)
+	}
+
+	for _, condition := range runtimeStatus.GetStatus().GetConditions() {
+		if condition.GetType() == cri.RuntimeReady && !condition.GetStatus() {
+			return fmt.Errorf("the CRI runtime is not ready: %s (%s)", condition.GetReason(), condition.GetMessage())
+		}
+	}
+	return nil
+}
+
 func connect(socketPath string, timeout time.Duration) (*grpc.ClientConn, error) {
 	if socketPath == "" {
 		return nil, fmt.Errorf("endpoint is not set")
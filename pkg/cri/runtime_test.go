@@ -57,6 +57,16 @@ var _ = Describe("CRI runtime", func() {
 			Expect(runtime.PodSandboxID(context.Background(), podUID)).To(Equal(podSandboxID))
 		})
 	})
+
+	It("is healthy when the runtime reports itself as ready", func() {
+		runtime = newDummyCrioRuntime()
+		Expect(runtime.Healthz(context.Background())).To(Succeed())
+	})
+
+	It("is not healthy when the runtime reports itself as not ready", func() {
+		runtime = newDummyCrioRuntime(fake.WithRuntimeNotReady())
+		Expect(runtime.Healthz(context.Background())).To(MatchError(HavePrefix("the CRI runtime is not ready")))
+	})
 })
 
 func newDummyCrioRuntime(opts ...fake.ClientOpt) *cri.Runtime {
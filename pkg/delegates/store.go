@@ -0,0 +1,138 @@
+package delegates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	configFileSuffix = ".json"
+	dirPermissions   = 0700
+	filePermissions  = 0600
+)
+
+// Store is an on-host store of the delegate configurations the attachments were plugged with,
+// indexed by their digest. It holds a file per configuration.
+type Store struct {
+	lock sync.Mutex
+	dir  string
+}
+
+// New returns a store persisted in the given directory, creating it when missing.
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create the delegate configurations directory %s: %w", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Digest returns the digest indexing the delegate configuration.
+func Digest(delegateConfig []byte) string {
+	digest := sha256.Sum256(delegateConfig)
+	return hex.EncodeToString(digest[:])
+}
+
+// Save durably stores the delegate configuration, returning its digest.
+func (s *Store) Save(delegateConfig []byte) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	digest := Digest(delegateConfig)
+	if _, err := os.Stat(s.path(digest)); err == nil {
+		return digest, nil
+	}
+	if err := s.write(digest, delegateConfig); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Load returns the delegate configuration of the given digest; the error wraps os.ErrNotExist
+// when it is not stored.
+func (s *Store) Load(digest string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delegateConfig, err := os.ReadFile(filepath.Clean(s.path(digest)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the delegate configuration %s: %w", digest, err)
+	}
+	return delegateConfig, nil
+}
+
+// Prune removes the delegate configurations no longer in use, per the given digests.
+func (s *Store) Prune(digestsInUse map[string]bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list the delegate configurations directory %s: %w", s.dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), configFileSuffix) {
+			continue
+		}
+		if digestsInUse[strings.TrimSuffix(entry.Name(), configFileSuffix)] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove the delegate configuration %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) path(digest string) string {
+	return filepath.Join(s.dir, digest+configFileSuffix)
+}
+
+// write stores the delegate configuration atomically: it is written to a temporary file, synced,
+// then renamed.
+func (s *Store) write(digest string, delegateConfig []byte) error {
+	tmpFile, err := os.CreateTemp(s.dir, digest+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create the delegate configuration %s: %w", digest, err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.Write(delegateConfig); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write the delegate configuration %s: %w", digest, err)
+	}
+	if err := tmpFile.Chmod(filePermissions); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write the delegate configuration %s: %w", digest, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to sync the delegate configuration %s: %w", digest, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write the delegate configuration %s: %w", digest, err)
+	}
+	if err := os.Rename(tmpFile.Name(), s.path(digest)); err != nil {
+		return fmt.Errorf("failed to write the delegate configuration %s: %w", digest, err)
+	}
+	return s.syncDir()
+}
+
+// syncDir persists the store directory entries, i.e. the creation and renaming of files.
+func (s *Store) syncDir() error {
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to open the delegate configurations directory %s: %w", s.dir, err)
+	}
+	defer func() { _ = dir.Close() }()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the delegate configurations directory %s: %w", s.dir, err)
+	}
+	return nil
+}
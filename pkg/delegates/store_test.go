@@ -0,0 +1,73 @@
+package delegates_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/delegates"
+)
+
+func TestDelegates(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Delegate configurations suite")
+}
+
+var _ = Describe("The delegate configurations store", func() {
+	const (
+		macvlanConfig = `{"cniVersion":"1.0.0","name":"net1","type":"macvlan"}`
+		bridgeConfig  = `{"cniVersion":"1.0.0","name":"net2","type":"bridge"}`
+	)
+
+	var (
+		dir   string
+		store *delegates.Store
+	)
+
+	BeforeEach(func() {
+		dir = filepath.Join(GinkgoT().TempDir(), "delegates")
+		var err error
+		store, err = delegates.New(dir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("stores the configurations by digest, across restarts", func() {
+		digest, err := store.Save([]byte(macvlanConfig))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest).To(Equal(delegates.Digest([]byte(macvlanConfig))))
+
+		reopenedStore, err := delegates.New(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopenedStore.Load(digest)).To(Equal([]byte(macvlanConfig)))
+	})
+
+	It("stores a configuration once", func() {
+		Expect(store.Save([]byte(macvlanConfig))).To(Equal(delegates.Digest([]byte(macvlanConfig))))
+		Expect(store.Save([]byte(macvlanConfig))).To(Equal(delegates.Digest([]byte(macvlanConfig))))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal(delegates.Digest([]byte(macvlanConfig)) + ".json"))
+	})
+
+	It("reports the configurations not stored", func() {
+		_, err := store.Load(delegates.Digest([]byte(macvlanConfig)))
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+
+	It("prunes the configurations no longer in use", func() {
+		macvlanDigest, err := store.Save([]byte(macvlanConfig))
+		Expect(err).NotTo(HaveOccurred())
+		bridgeDigest, err := store.Save([]byte(bridgeConfig))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Prune(map[string]bool{bridgeDigest: true})).To(Succeed())
+		_, err = store.Load(macvlanDigest)
+		Expect(err).To(MatchError(os.ErrNotExist))
+		Expect(store.Load(bridgeDigest)).To(Equal([]byte(bridgeConfig)))
+	})
+})
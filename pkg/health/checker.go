@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Check reports an error when the component it probes is not healthy.
+type Check func(ctx context.Context) error
+
+// Checker aggregates the named liveness and readiness checks of the controller.
+type Checker struct {
+	lock      sync.RWMutex
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+// NewChecker returns a Checker without any registered checks.
+func NewChecker() *Checker {
+	return &Checker{
+		liveness:  map[string]Check{},
+		readiness: map[string]Check{},
+	}
+}
+
+// AddLivenessCheck registers a check served by the liveness handler.
+func (c *Checker) AddLivenessCheck(name string, check Check) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.liveness[name] = check
+}
+
+// AddReadinessCheck registers a check served by the readiness handler.
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.readiness[name] = check
+}
+
+// LivenessHandler returns an HTTP handler replying OK only when all liveness checks pass.
+func (c *Checker) LivenessHandler() http.Handler {
+	return c.handler(func() map[string]Check { return c.liveness })
+}
+
+// ReadinessHandler returns an HTTP handler replying OK only when all readiness checks pass.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return c.handler(func() map[string]Check { return c.readiness })
+}
+
+func (c *Checker) handler(checks func() map[string]Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		c.lock.RLock()
+		report, healthy := runChecks(ctx, checks())
+		c.lock.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if !healthy {
+			klog.V(logging.Debug).InfoS("check failed", "path", r.URL.Path, "report", report)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = fmt.Fprint(w, report)
+	})
+}
+
+func runChecks(ctx context.Context, checks map[string]Check) (string, bool) {
+	checkNames := make([]string, 0, len(checks))
+	for name := range checks {
+		checkNames = append(checkNames, name)
+	}
+	sort.Strings(checkNames)
+
+	healthy := true
+	var report strings.Builder
+	for _, name := range checkNames {
+		if err := checks[name](ctx); err != nil {
+			healthy = false
+			fmt.Fprintf(&report, "[-]%s failed: %v\n", name, err)
+			continue
+		}
+		fmt.Fprintf(&report, "[+]%s ok\n", name)
+	}
+	return report.String(), healthy
+}
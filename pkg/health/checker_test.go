@@ -0,0 +1,71 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/health"
+)
+
+func TestHealth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamic networks controller health suite")
+}
+
+var _ = Describe("The health checker", func() {
+	var checker *health.Checker
+
+	healthy := func(context.Context) error { return nil }
+	unhealthy := func(context.Context) error { return errors.New("kaboom") }
+
+	probe := func(handler http.Handler) (int, string) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL) // #nosec
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	BeforeEach(func() {
+		checker = health.NewChecker()
+	})
+
+	It("replies OK when all the checks pass", func() {
+		checker.AddReadinessCheck("cri", healthy)
+		checker.AddReadinessCheck("multus", healthy)
+
+		statusCode, report := probe(checker.ReadinessHandler())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(report).To(Equal("[+]cri ok\n[+]multus ok\n"))
+	})
+
+	It("replies with an error when any of the checks fails", func() {
+		checker.AddReadinessCheck("cri", healthy)
+		checker.AddReadinessCheck("multus", unhealthy)
+
+		statusCode, report := probe(checker.ReadinessHandler())
+		Expect(statusCode).To(Equal(http.StatusInternalServerError))
+		Expect(report).To(Equal("[+]cri ok\n[-]multus failed: kaboom\n"))
+	})
+
+	It("keeps the liveness and readiness checks apart", func() {
+		checker.AddLivenessCheck("workers", healthy)
+		checker.AddReadinessCheck("multus", unhealthy)
+
+		statusCode, report := probe(checker.LivenessHandler())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(report).To(Equal("[+]workers ok\n"))
+	})
+})
@@ -0,0 +1,173 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+const (
+	journalFileSuffix = ".json"
+	dirPermissions    = 0700
+	filePermissions   = 0600
+)
+
+// Operation is the intent to invoke a CNI delegate on behalf of a pod, recorded before the
+// delegate is invoked.
+type Operation struct {
+	Command                 string                        `json:"command"`
+	PodNamespace            string                        `json:"podNamespace"`
+	PodName                 string                        `json:"podName"`
+	PodUID                  string                        `json:"podUID"`
+	PodSandboxID            string                        `json:"podSandboxID"`
+	PodNetNS                string                        `json:"podNetNS"`
+	NetworkSelectionElement nadv1.NetworkSelectionElement `json:"networkSelectionElement"`
+	DelegateConfig          json.RawMessage               `json:"delegateConfig,omitempty"`
+}
+
+// Journal is an on-host write-ahead log of the CNI operations performed on the pods, whose
+// outcome is not yet recorded in their network-status. It holds a file per pod.
+type Journal struct {
+	lock sync.Mutex
+	dir  string
+}
+
+// New returns a journal persisted in the given directory, creating it when missing.
+func New(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create the journal directory %s: %w", dir, err)
+	}
+	return &Journal{dir: dir}, nil
+}
+
+// Record durably appends the operation to the journal of its pod.
+func (j *Journal) Record(operation Operation) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	operations, err := j.read(j.path(operation.PodUID))
+	if err != nil {
+		return err
+	}
+	return j.write(operation.PodUID, append(operations, operation))
+}
+
+// Complete discards the journal of the pod, once the outcome of its operations is recorded.
+func (j *Journal) Complete(podUID string) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if err := os.Remove(j.path(podUID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to complete the journal of pod %s: %w", podUID, err)
+	}
+	return j.syncDir()
+}
+
+// Operations returns the journaled operations of the pod.
+func (j *Journal) Operations(podUID string) ([]Operation, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.read(j.path(podUID))
+}
+
+// Pending returns the journaled operations, indexed by pod UID.
+func (j *Journal) Pending() (map[string][]Operation, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the journal directory %s: %w", j.dir, err)
+	}
+
+	pending := map[string][]Operation{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), journalFileSuffix) {
+			continue
+		}
+		operations, err := j.read(filepath.Join(j.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(operations) > 0 {
+			pending[strings.TrimSuffix(entry.Name(), journalFileSuffix)] = operations
+		}
+	}
+	return pending, nil
+}
+
+func (j *Journal) path(podUID string) string {
+	return filepath.Join(j.dir, podUID+journalFileSuffix)
+}
+
+func (j *Journal) read(path string) ([]Operation, error) {
+	contents, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the journal %s: %w", path, err)
+	}
+
+	var operations []Operation
+	if err := json.Unmarshal(contents, &operations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the journal %s: %w", path, err)
+	}
+	return operations, nil
+}
+
+// write replaces the journal of the pod atomically: the operations are written to a temporary
+// file, synced, then renamed over the journal.
+func (j *Journal) write(podUID string, operations []Operation) error {
+	contents, err := json.Marshal(operations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the journal of pod %s: %w", podUID, err)
+	}
+
+	tmpFile, err := os.CreateTemp(j.dir, podUID+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create the journal of pod %s: %w", podUID, err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.Write(contents); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write the journal of pod %s: %w", podUID, err)
+	}
+	if err := tmpFile.Chmod(filePermissions); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write the journal of pod %s: %w", podUID, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to sync the journal of pod %s: %w", podUID, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write the journal of pod %s: %w", podUID, err)
+	}
+	if err := os.Rename(tmpFile.Name(), j.path(podUID)); err != nil {
+		return fmt.Errorf("failed to write the journal of pod %s: %w", podUID, err)
+	}
+	return j.syncDir()
+}
+
+// syncDir persists the journal directory entries, i.e. the creation, renaming and removal of files.
+func (j *Journal) syncDir() error {
+	dir, err := os.Open(j.dir)
+	if err != nil {
+		return fmt.Errorf("failed to open the journal directory %s: %w", j.dir, err)
+	}
+	defer func() { _ = dir.Close() }()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the journal directory %s: %w", j.dir, err)
+	}
+	return nil
+}
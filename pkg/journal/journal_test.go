@@ -0,0 +1,96 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/journal"
+)
+
+func TestJournal(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Operations journal suite")
+}
+
+var _ = Describe("The operations journal", func() {
+	var (
+		dir               string
+		operationsJournal *journal.Journal
+	)
+
+	operation := func(podUID string, command string, ifaceName string) journal.Operation {
+		return journal.Operation{
+			Command:      command,
+			PodNamespace: "ns1",
+			PodName:      "pod-" + podUID,
+			PodUID:       podUID,
+			PodSandboxID: "sandbox",
+			PodNetNS:     "/var/run/netns/" + podUID,
+			NetworkSelectionElement: nadv1.NetworkSelectionElement{
+				Name:             "net1",
+				Namespace:        "ns1",
+				InterfaceRequest: ifaceName,
+				MacRequest:       "02:03:04:05:06:07",
+			},
+			DelegateConfig: []byte(`{"cniVersion":"1.0.0","name":"net1","type":"macvlan"}`),
+		}
+	}
+
+	BeforeEach(func() {
+		dir = filepath.Join(GinkgoT().TempDir(), "journal")
+		var err error
+		operationsJournal, err = journal.New(dir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("is empty when created", func() {
+		Expect(operationsJournal.Pending()).To(BeEmpty())
+	})
+
+	It("records the operations per pod, in order, across restarts", func() {
+		Expect(operationsJournal.Record(operation("uid1", "ADD", "net1"))).To(Succeed())
+		Expect(operationsJournal.Record(operation("uid2", "DEL", "net1"))).To(Succeed())
+		Expect(operationsJournal.Record(operation("uid1", "ADD", "net2"))).To(Succeed())
+
+		reopenedJournal, err := journal.New(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopenedJournal.Pending()).To(Equal(map[string][]journal.Operation{
+			"uid1": {operation("uid1", "ADD", "net1"), operation("uid1", "ADD", "net2")},
+			"uid2": {operation("uid2", "DEL", "net1")},
+		}))
+	})
+
+	It("returns the operations of a given pod", func() {
+		Expect(operationsJournal.Record(operation("uid1", "ADD", "net1"))).To(Succeed())
+		Expect(operationsJournal.Record(operation("uid2", "ADD", "net1"))).To(Succeed())
+
+		Expect(operationsJournal.Operations("uid1")).To(Equal([]journal.Operation{operation("uid1", "ADD", "net1")}))
+		Expect(operationsJournal.Operations("uid-without-operations")).To(BeEmpty())
+	})
+
+	It("discards the operations of a pod once completed", func() {
+		Expect(operationsJournal.Record(operation("uid1", "ADD", "net1"))).To(Succeed())
+		Expect(operationsJournal.Record(operation("uid2", "ADD", "net1"))).To(Succeed())
+
+		Expect(operationsJournal.Complete("uid1")).To(Succeed())
+		Expect(operationsJournal.Pending()).To(Equal(map[string][]journal.Operation{
+			"uid2": {operation("uid2", "ADD", "net1")},
+		}))
+		Expect(operationsJournal.Complete("uid-without-operations")).To(Succeed())
+	})
+
+	It("leaves no temporary files behind", func() {
+		Expect(operationsJournal.Record(operation("uid1", "ADD", "net1"))).To(Succeed())
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("uid1.json"))
+	})
+})
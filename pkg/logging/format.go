@@ -0,0 +1,16 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+// UseJSON has klog - along with the contextual loggers - log JSON objects, one per line, to the output.
+// The messages logged at a verbosity above the klog one are discarded, as they are in the text format.
+func UseJSON(output io.Writer, verbosity int) {
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{Level: slog.Level(-verbosity)})
+	klog.SetLoggerWithOptions(logr.FromSlogHandler(handler), klog.ContextualLogger(true))
+}
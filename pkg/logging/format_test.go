@@ -0,0 +1,58 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+func TestLogging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamic networks controller logging suite")
+}
+
+var _ = Describe("The JSON logging", func() {
+	var output *bytes.Buffer
+
+	lines := func() []map[string]interface{} {
+		var lines []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+			entry := map[string]interface{}{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			lines = append(lines, entry)
+		}
+		return lines
+	}
+
+	BeforeEach(func() {
+		output = &bytes.Buffer{}
+		logging.UseJSON(output, logging.Debug)
+		DeferCleanup(klog.ClearLogger)
+	})
+
+	It("logs the key-values of the contextual loggers", func() {
+		klog.Background().WithValues("pod", klog.KRef("default", "tiny-winy-pod")).Info("adding the attachment", "interface", "net1")
+
+		Expect(lines()).To(ConsistOf(SatisfyAll(
+			HaveKeyWithValue("msg", "adding the attachment"),
+			HaveKeyWithValue("pod", map[string]interface{}{"name": "tiny-winy-pod", "namespace": "default"}),
+			HaveKeyWithValue("interface", "net1"),
+		)))
+	})
+
+	It("discards the messages above the verbosity", func() {
+		logger := klog.Background()
+		logger.V(logging.Debug).Info("debugging")
+		logger.V(logging.Debug + 1).Info("tracing")
+
+		Expect(lines()).To(ConsistOf(HaveKeyWithValue("msg", "debugging")))
+	})
+})
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	namespace = "dynamic_networks_controller"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Metrics holds the prometheus collectors exposed by the dynamic networks controller.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	attachmentOperations *prometheus.CounterVec
+	delegateDuration     *prometheus.HistogramVec
+	criRequestDuration   *prometheus.HistogramVec
+	interfaceDrifts      *prometheus.CounterVec
+	retryBackoff         *prometheus.HistogramVec
+	podsInBackoff        prometheus.Gauge
+
+	workqueueDepth                   *prometheus.GaugeVec
+	workqueueAdds                    *prometheus.CounterVec
+	workqueueLatency                 *prometheus.HistogramVec
+	workqueueWorkDuration            *prometheus.HistogramVec
+	workqueueUnfinishedWork          *prometheus.GaugeVec
+	workqueueLongestRunningProcessor *prometheus.GaugeVec
+	workqueueRetries                 *prometheus.CounterVec
+}
+
+// New returns a Metrics instance whose collectors are registered in a dedicated registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		attachmentOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "attachment_operations_total",
+			Help:      "Number of dynamic attachment operations, partitioned by CNI command, network, result and failure reason.",
+		}, []string{"operation", "network_namespace", "network_name", "result", "reason"}),
+		delegateDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "delegate_duration_seconds",
+			Help:      "Latency of the multus delegate invocations, partitioned by CNI command and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+		}, []string{"command", "result"}),
+		criRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cri_request_duration_seconds",
+			Help:      "Latency of the CRI lookups, partitioned by method and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+		}, []string{"method", "result"}),
+		interfaceDrifts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "interface_drifts_total",
+			Help:      "Number of interfaces drifting from the pods network-status found by the auditor, partitioned by kind.",
+		}, []string{"kind"}),
+		retryBackoff: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "retry_backoff_seconds",
+			Help:      "Delay before the failed pod reconciliations are retried, partitioned by error class.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 13),
+		}, []string{"error_class"}),
+		podsInBackoff: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pods_in_backoff",
+			Help:      "Number of pods whose reconciliation failed, and is being retried.",
+		}),
+		workqueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "depth",
+			Help:      "Current depth of the workqueue.",
+		}, []string{"name"}),
+		workqueueAdds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "adds_total",
+			Help:      "Total number of adds handled by the workqueue.",
+		}, []string{"name"}),
+		workqueueLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "queue_duration_seconds",
+			Help:      "How long in seconds an item stays in the workqueue before being requested.",
+			Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+		}, []string{"name"}),
+		workqueueWorkDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "work_duration_seconds",
+			Help:      "How long in seconds processing an item from the workqueue takes.",
+			Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+		}, []string{"name"}),
+		workqueueUnfinishedWork: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "unfinished_work_seconds",
+			Help:      "How many seconds of work has been done that is in progress and hasn't been observed by work_duration.",
+		}, []string{"name"}),
+		workqueueLongestRunningProcessor: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "longest_running_processor_seconds",
+			Help:      "How many seconds has the longest running processor for the workqueue been running.",
+		}, []string{"name"}),
+		workqueueRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "workqueue",
+			Name:      "retries_total",
+			Help:      "Total number of retries handled by the workqueue.",
+		}, []string{"name"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.attachmentOperations,
+		m.delegateDuration,
+		m.criRequestDuration,
+		m.interfaceDrifts,
+		m.retryBackoff,
+		m.podsInBackoff,
+		m.workqueueDepth,
+		m.workqueueAdds,
+		m.workqueueLatency,
+		m.workqueueWorkDuration,
+		m.workqueueUnfinishedWork,
+		m.workqueueLongestRunningProcessor,
+		m.workqueueRetries,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the registered metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// AttachmentSucceeded accounts for a successful CNI operation on the given network.
+func (m *Metrics) AttachmentSucceeded(operation, networkNamespace, networkName string) {
+	m.attachmentOperations.WithLabelValues(operation, networkNamespace, networkName, resultSuccess, "").Inc()
+}
+
+// AttachmentFailed accounts for a failed CNI operation on the given network.
+func (m *Metrics) AttachmentFailed(operation, networkNamespace, networkName, reason string) {
+	m.attachmentOperations.WithLabelValues(operation, networkNamespace, networkName, resultFailure, reason).Inc()
+}
+
+// ObserveDelegate records the latency of a multus delegate invocation.
+func (m *Metrics) ObserveDelegate(command string, duration time.Duration, err error) {
+	m.delegateDuration.WithLabelValues(command, result(err)).Observe(duration.Seconds())
+}
+
+// ObserveCRIRequest records the latency of a CRI lookup.
+func (m *Metrics) ObserveCRIRequest(method string, duration time.Duration, err error) {
+	m.criRequestDuration.WithLabelValues(method, result(err)).Observe(duration.Seconds())
+}
+
+// InterfaceDrift accounts for an interface drifting from the pod network-status: either missing
+// from the pod network namespace, or orphaned.
+func (m *Metrics) InterfaceDrift(kind string) {
+	m.interfaceDrifts.WithLabelValues(kind).Inc()
+}
+
+// ObserveRetryBackoff records the delay before a pod reconciliation failed with the given class of
+// errors is retried.
+func (m *Metrics) ObserveRetryBackoff(errorClass string, delay time.Duration) {
+	m.retryBackoff.WithLabelValues(errorClass).Observe(delay.Seconds())
+}
+
+// BackoffStarted accounts for a pod whose reconciliation started being retried.
+func (m *Metrics) BackoffStarted() {
+	m.podsInBackoff.Inc()
+}
+
+// BackoffEnded accounts for a pod no longer being retried: its reconciliation succeeded, or it is gone.
+func (m *Metrics) BackoffEnded() {
+	m.podsInBackoff.Dec()
+}
+
+// WorkqueueMetricsProvider returns a workqueue.MetricsProvider backed by this Metrics instance.
+func (m *Metrics) WorkqueueMetricsProvider() workqueue.MetricsProvider {
+	return &workqueueMetricsProvider{metrics: m}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+	return resultSuccess
+}
+
+type workqueueMetricsProvider struct {
+	metrics *Metrics
+}
+
+func (p *workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return p.metrics.workqueueDepth.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return p.metrics.workqueueAdds.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return p.metrics.workqueueLatency.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return p.metrics.workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.metrics.workqueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.metrics.workqueueLongestRunningProcessor.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return p.metrics.workqueueRetries.WithLabelValues(name)
+}
@@ -0,0 +1,114 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamic networks controller metrics suite")
+}
+
+var _ = Describe("The dynamic networks controller metrics", func() {
+	var controllerMetrics *metrics.Metrics
+
+	BeforeEach(func() {
+		controllerMetrics = metrics.New()
+	})
+
+	scrape := func() string {
+		server := httptest.NewServer(controllerMetrics.Handler())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL) // #nosec
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	It("accounts for successful attachment operations", func() {
+		controllerMetrics.AttachmentSucceeded("ADD", "ns1", "net1")
+
+		Expect(scrape()).To(ContainSubstring(
+			`dynamic_networks_controller_attachment_operations_total{network_name="net1",network_namespace="ns1",operation="ADD",reason="",result="success"} 1`,
+		))
+	})
+
+	It("accounts for failed attachment operations along with the failure reason", func() {
+		controllerMetrics.AttachmentFailed("DEL", "ns1", "net1", "DelegateFailed")
+		controllerMetrics.AttachmentFailed("DEL", "ns1", "net1", "DelegateFailed")
+
+		Expect(scrape()).To(ContainSubstring(
+			`dynamic_networks_controller_attachment_operations_total{network_name="net1",network_namespace="ns1",operation="DEL",reason="DelegateFailed",result="failure"} 2`,
+		))
+	})
+
+	It("records the delegate and CRI latencies", func() {
+		controllerMetrics.ObserveDelegate("ADD", time.Second, nil)
+		controllerMetrics.ObserveCRIRequest("PodSandboxID", time.Millisecond, errors.New("boom"))
+
+		Expect(scrape()).To(And(
+			ContainSubstring(`dynamic_networks_controller_delegate_duration_seconds_count{command="ADD",result="success"} 1`),
+			ContainSubstring(`dynamic_networks_controller_cri_request_duration_seconds_count{method="PodSandboxID",result="failure"} 1`),
+		))
+	})
+
+	It("accounts for the interfaces drifting from the pods network-status", func() {
+		controllerMetrics.InterfaceDrift("missing")
+		controllerMetrics.InterfaceDrift("orphaned")
+		controllerMetrics.InterfaceDrift("orphaned")
+
+		Expect(scrape()).To(And(
+			ContainSubstring(`dynamic_networks_controller_interface_drifts_total{kind="missing"} 1`),
+			ContainSubstring(`dynamic_networks_controller_interface_drifts_total{kind="orphaned"} 2`),
+		))
+	})
+
+	It("exposes the retry backoff, and the pods backing off", func() {
+		controllerMetrics.BackoffStarted()
+		controllerMetrics.ObserveRetryBackoff("Transient", 100*time.Millisecond)
+		controllerMetrics.BackoffStarted()
+		controllerMetrics.ObserveRetryBackoff("Transient", 200*time.Millisecond)
+		controllerMetrics.BackoffEnded()
+
+		Expect(scrape()).To(And(
+			ContainSubstring(`dynamic_networks_controller_retry_backoff_seconds_count{error_class="Transient"} 2`),
+			ContainSubstring(`dynamic_networks_controller_pods_in_backoff 1`),
+		))
+	})
+
+	It("exposes the workqueue depth and retries", func() {
+		const queueName = "test-queue"
+		queue := workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{
+				Name:            queueName,
+				MetricsProvider: controllerMetrics.WorkqueueMetricsProvider(),
+			},
+		)
+		defer queue.ShutDown()
+
+		queue.Add("ns1/pod1")
+		queue.AddRateLimited("ns1/pod2")
+
+		Expect(scrape()).To(And(
+			ContainSubstring(`dynamic_networks_controller_workqueue_depth{name="test-queue"} 1`),
+			ContainSubstring(`dynamic_networks_controller_workqueue_retries_total{name="test-queue"} 1`),
+		))
+	})
+})
@@ -9,14 +9,20 @@ import (
 	"net"
 	"net/http"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
 	"k8s.io/klog/v2"
 
 	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
 )
 
 const (
-	CmdAdd = "ADD"
-	CmdDel = "DEL"
+	CmdAdd    = "ADD"
+	CmdDel    = "DEL"
+	CmdCheck  = "CHECK"
+	CmdGC     = "GC"
+	CmdStatus = "STATUS"
 )
 
 func MultusDelegateURL() string {
@@ -24,13 +30,32 @@ func MultusDelegateURL() string {
 	return multusapi.GetAPIEndpoint(delegateEndpoint)
 }
 
+// MultusHealthURL returns the URL of the multus server health endpoint
+func MultusHealthURL() string {
+	return multusapi.GetAPIEndpoint(multusapi.MultusHealthAPIEndpoint)
+}
+
 type Client interface {
-	InvokeDelegate(req *multusapi.Request) (*multusapi.Response, error)
+	// InvokeDelegate sends the CNI request to the multus server; the request is abandoned once the
+	// context is done.
+	InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error)
+}
+
+// ResponseError is returned when the multus server answers the CNI request with a failure - e.g.
+// the delegate plugin rejected it.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected CNI response status %v: '%s'", e.StatusCode, e.Body)
 }
 
 type HTTPClient struct {
 	httpClient *http.Client
 	serverURL  string
+	healthURL  string
 }
 
 func NewClient(socketPath string) *HTTPClient {
@@ -43,11 +68,34 @@ func NewClient(socketPath string) *HTTPClient {
 			},
 		},
 		serverURL: MultusDelegateURL(),
+		healthURL: MultusHealthURL(),
+	}
+}
+
+// Healthz checks the multus server answers on its health endpoint
+func (c *HTTPClient) Healthz(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.healthURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to reach the multus server: %v", err)
+	}
+	defer func() {
+		if err = resp.Body.Close(); err != nil {
+			klog.ErrorS(err, "failed closing the connection to the multus-server")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected multus server health status %v", resp.StatusCode)
 	}
+	return nil
 }
 
-func (c *HTTPClient) InvokeDelegate(req *multusapi.Request) (*multusapi.Response, error) {
-	httpResp, err := c.DoCNI(req)
+func (c *HTTPClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	httpResp, err := c.DoCNI(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -60,13 +108,13 @@ func (c *HTTPClient) InvokeDelegate(req *multusapi.Request) (*multusapi.Response
 	return response, nil
 }
 
-func (c *HTTPClient) DoCNI(req *multusapi.Request) ([]byte, error) {
+func (c *HTTPClient) DoCNI(ctx context.Context, req *multusapi.Request) ([]byte, error) {
 	data, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal CNI request %v: %v", req, err)
 	}
 
-	request, err := httpRequest(c.serverURL, data)
+	request, err := httpRequest(ctx, c.serverURL, data)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +124,7 @@ func (c *HTTPClient) DoCNI(req *multusapi.Request) ([]byte, error) {
 	}
 	defer func() {
 		if err = resp.Body.Close(); err != nil {
-			klog.Errorf("failed closing the connection to the multus-server: %v", err)
+			klog.ErrorS(err, "failed closing the connection to the multus-server")
 		}
 	}()
 
@@ -86,17 +134,19 @@ func (c *HTTPClient) DoCNI(req *multusapi.Request) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected CNI response status %v: '%s'", resp.StatusCode, string(body))
+		return nil, &ResponseError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return body, nil
 }
 
-func httpRequest(serverURL string, payload []byte) (*http.Request, error) {
-	httpReq, err := http.NewRequestWithContext(context.Background(), http.MethodPost, serverURL, bytes.NewBuffer(payload))
+func httpRequest(ctx context.Context, serverURL string, payload []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL, bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	// the trace context travels to the multus server, for its spans to join the controller's trace
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
 	return httpReq, nil
 }
@@ -1,6 +1,7 @@
 package multuscni
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"net/http"
@@ -12,6 +13,10 @@ import (
 
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
 )
 
@@ -34,8 +39,9 @@ var _ = Describe("multuscni REST client", func() {
 		}))
 
 		defer server.Close()
-		_, err := newDummyClient(server.Client(), server.URL).InvokeDelegate(multusRequest())
+		_, err := newDummyClient(server.Client(), server.URL).InvokeDelegate(context.Background(), multusRequest())
 		Expect(err).To(MatchError("unexpected CNI response status 400: 'kablewit'"))
+		Expect(err).To(Equal(&ResponseError{StatusCode: http.StatusBadRequest, Body: "kablewit"}))
 	})
 
 	It("errors when the service replies with anything other than a `multusapi.Response` structure", func() {
@@ -45,10 +51,54 @@ var _ = Describe("multuscni REST client", func() {
 		}))
 
 		defer server.Close()
-		_, err := newDummyClient(server.Client(), server.URL).InvokeDelegate(multusRequest())
+		_, err := newDummyClient(server.Client(), server.URL).InvokeDelegate(context.Background(), multusRequest())
 		Expect(err).To(MatchError(ContainSubstring("failed to unmarshal response '{asd:123}':")))
 	})
 
+	It("propagates the trace context to the server", func() {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		DeferCleanup(otel.SetTextMapPropagator, otel.GetTextMapPropagator())
+
+		var traceParent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceParent = r.Header.Get("traceparent")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x0a, 0x0b},
+			SpanID:     trace.SpanID{0x0c, 0x0d},
+			TraceFlags: trace.FlagsSampled,
+		})
+		_, err := newDummyClient(server.Client(), server.URL).InvokeDelegate(
+			trace.ContextWithSpanContext(context.Background(), spanContext),
+			multusRequest(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(traceParent).To(Equal("00-0a0b0000000000000000000000000000-0c0d000000000000-01"))
+	})
+
+	It("is healthy when the server replies to the health endpoint", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		defer server.Close()
+		Expect(newDummyClient(server.Client(), server.URL).Healthz(context.Background())).To(Succeed())
+	})
+
+	It("is not healthy when the server health endpoint replies with an error", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		defer server.Close()
+		Expect(
+			newDummyClient(server.Client(), server.URL).Healthz(context.Background()),
+		).To(MatchError("unexpected multus server health status 503"))
+	})
+
 	DescribeTable("return the expected response", func(response *multusapi.Response) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -57,7 +107,7 @@ var _ = Describe("multuscni REST client", func() {
 		}))
 
 		defer server.Close()
-		Expect(newDummyClient(server.Client(), server.URL).InvokeDelegate(multusRequest())).To(Equal(response))
+		Expect(newDummyClient(server.Client(), server.URL).InvokeDelegate(context.Background(), multusRequest())).To(Equal(response))
 	},
 		Entry(
 			"when the server replies with a simple L2 CNI result",
@@ -89,7 +139,7 @@ func multusRequest() *multusapi.Request {
 }
 
 func newDummyClient(httpClient *http.Client, serverURL string) *HTTPClient {
-	return &HTTPClient{httpClient: httpClient, serverURL: serverURL}
+	return &HTTPClient{httpClient: httpClient, serverURL: serverURL, healthURL: serverURL}
 }
 
 func cniInterface(networkName, macAddress string) *cni100.Interface {
@@ -0,0 +1,172 @@
+package multuscni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+)
+
+// DirectClient invokes the CNI plugins binaries using libcni, instead of delegating the CNI requests to
+// the multus server: the controller then works with multus thin, or without multus at all.
+type DirectClient struct {
+	cniConfig *libcni.CNIConfig
+	binDirs   []string
+	confDir   string
+}
+
+// NewDirectClient returns a client invoking the CNI plugins found in the bin directories - a
+// colon-separated list. The network configurations holding nothing but their name are loaded from the
+// conf directory, while the results of the attachments are cached in the cache directory.
+func NewDirectClient(binDir, confDir, cacheDir string) *DirectClient {
+	binDirs := filepath.SplitList(binDir)
+	return &DirectClient{
+		cniConfig: libcni.NewCNIConfigWithCacheDir(binDirs, cacheDir, nil),
+		binDirs:   binDirs,
+		confDir:   confDir,
+	}
+}
+
+// Healthz checks the CNI plugins bin directories can be accessed
+func (c *DirectClient) Healthz(_ context.Context) error {
+	for _, binDir := range c.binDirs {
+		if _, err := os.Stat(binDir); err != nil {
+			return fmt.Errorf("failed to access the CNI plugins directory: %v", err)
+		}
+	}
+	return nil
+}
+
+// InvokeDelegate runs the CNI request - as built for the multus server - against the plugins of the
+// network configuration, inside the network namespace of the request.
+func (c *DirectClient) InvokeDelegate(ctx context.Context, req *multusapi.Request) (*multusapi.Response, error) {
+	networkConfigList, err := c.networkConfigList(req.Config, req.InterfaceAttributes)
+	if err != nil {
+		return nil, err
+	}
+	runtimeConf := runtimeConf(req)
+
+	switch command := req.Env["CNI_COMMAND"]; command {
+	case CmdAdd:
+		result, err := c.cniConfig.AddNetworkList(ctx, networkConfigList, runtimeConf)
+		if err != nil {
+			return nil, err
+		}
+		convertedResult, err := cni100.NewResultFromResult(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert the CNI result: %v", err)
+		}
+		return &multusapi.Response{Result: convertedResult}, nil
+	case CmdDel:
+		return &multusapi.Response{}, c.cniConfig.DelNetworkList(ctx, networkConfigList, runtimeConf)
+	case CmdCheck:
+		return &multusapi.Response{}, c.cniConfig.CheckNetworkList(ctx, networkConfigList, runtimeConf)
+	case CmdGC:
+		gcArgs, err := gcArgs(req.Config)
+		if err != nil {
+			return nil, err
+		}
+		return &multusapi.Response{}, c.cniConfig.GCNetworkList(ctx, networkConfigList, gcArgs)
+	case CmdStatus:
+		return &multusapi.Response{}, c.cniConfig.GetStatusNetworkList(ctx, networkConfigList)
+	default:
+		return nil, fmt.Errorf("unsupported CNI command: %q", command)
+	}
+}
+
+// networkConfigList parses the network configuration - either a list of plugins or a single one - of the
+// request. As multus does, a configuration holding nothing but its name is loaded from the conf directory.
+func (c *DirectClient) networkConfigList(
+	config []byte,
+	interfaceAttributes *multusapi.DelegateInterfaceAttributes,
+) (*libcni.NetworkConfigList, error) {
+	rawConfig := map[string]interface{}{}
+	if err := json.Unmarshal(config, &rawConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the CNI configuration: %v", err)
+	}
+
+	var networkConfigList *libcni.NetworkConfigList
+	var err error
+	_, isList := rawConfig["plugins"]
+	_, hasType := rawConfig["type"]
+	switch {
+	case isList:
+		networkConfigList, err = libcni.ConfListFromBytes(config)
+	case hasType:
+		var networkConfig *libcni.NetworkConfig
+		networkConfig, err = libcni.ConfFromBytes(config)
+		if err == nil {
+			networkConfigList, err = libcni.ConfListFromConf(networkConfig)
+		}
+	default:
+		networkName, _ := rawConfig["name"].(string)
+		networkConfigList, err = libcni.LoadConfList(c.confDir, networkName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the CNI configuration: %v", err)
+	}
+
+	// the CNI args of the attachment are passed to each plugin, as multus does
+	if interfaceAttributes != nil && interfaceAttributes.CNIArgs != nil {
+		for i := range networkConfigList.Plugins {
+			networkConfigList.Plugins[i], err = libcni.InjectConf(
+				networkConfigList.Plugins[i],
+				map[string]interface{}{"args": map[string]interface{}{"cni": *interfaceAttributes.CNIArgs}},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inject the CNI args: %v", err)
+			}
+		}
+	}
+	return networkConfigList, nil
+}
+
+// runtimeConf translates the CNI environment of the request - along with the IPs and MAC requested for
+// the interface, as capabilities - to the libcni runtime configuration.
+func runtimeConf(req *multusapi.Request) *libcni.RuntimeConf {
+	containerID := req.Env["CNI_CONTAINERID"]
+	runtimeConf := &libcni.RuntimeConf{
+		ContainerID:    containerID,
+		NetNS:          req.Env["CNI_NETNS"],
+		IfName:         req.Env["CNI_IFNAME"],
+		Args:           [][2]string{{"IgnoreUnknown", "true"}},
+		CapabilityArgs: map[string]interface{}{},
+	}
+	for _, arg := range strings.Split(req.Env["CNI_ARGS"], ";") {
+		if key, value, isKeyValue := strings.Cut(arg, "="); isKeyValue {
+			runtimeConf.Args = append(runtimeConf.Args, [2]string{key, value})
+		}
+	}
+	if containerID != "" {
+		runtimeConf.Args = append(runtimeConf.Args, [2]string{"K8S_POD_INFRA_CONTAINER_ID", containerID})
+	}
+
+	if attributes := req.InterfaceAttributes; attributes != nil {
+		if len(attributes.IPRequest) > 0 {
+			runtimeConf.CapabilityArgs["ips"] = attributes.IPRequest
+		}
+		if attributes.MacRequest != "" {
+			runtimeConf.CapabilityArgs["mac"] = attributes.MacRequest
+		}
+	}
+	return runtimeConf
+}
+
+// gcArgs reads the valid attachments listed in the GC request configuration
+func gcArgs(config []byte) (*libcni.GCArgs, error) {
+	var gcConfig struct {
+		ValidAttachments []cnitypes.GCAttachment `json:"cni.dev/valid-attachments"`
+	}
+	if err := json.Unmarshal(config, &gcConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the valid attachments: %v", err)
+	}
+	return &libcni.GCArgs{ValidAttachments: gcConfig.ValidAttachments}, nil
+}
@@ -0,0 +1,124 @@
+package multuscni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+)
+
+var _ = Describe("The direct CNI client", func() {
+	const (
+		containerID = "1234"
+		netnsPath   = "/var/run/netns/tiny-netns"
+		pluginName  = "tiny-plugin"
+	)
+
+	// the plugin logs its invocations, and answers ADD with the interface it was requested to create
+	const plugin = `#!/bin/sh
+cat > /dev/null
+echo "$CNI_COMMAND $CNI_IFNAME $CNI_ARGS" >> "$(dirname "$0")/invocations"
+if [ "$CNI_IFNAME" = "net7" ]; then
+    echo '{"cniVersion": "1.0.0", "code": 7, "msg": "kablewit"}'
+    exit 1
+fi
+if [ "$CNI_COMMAND" = "ADD" ]; then
+    echo "{\"cniVersion\": \"1.0.0\", \"interfaces\": [{\"name\": \"$CNI_IFNAME\", \"sandbox\": \"$CNI_NETNS\"}]}"
+fi
+`
+
+	var (
+		binDir  string
+		confDir string
+		client  *DirectClient
+	)
+
+	invocations := func() []string {
+		contents, err := os.ReadFile(filepath.Join(binDir, "invocations"))
+		Expect(err).NotTo(HaveOccurred())
+		return strings.Split(strings.TrimSpace(string(contents)), "\n")
+	}
+
+	request := func(command, ifaceName string, config string) *multusapi.Request {
+		return multusapi.CreateDelegateRequest(
+			command, containerID, netnsPath, ifaceName, "default", "tiny-winy-pod", "abc-def", []byte(config), nil)
+	}
+
+	BeforeEach(func() {
+		binDir = GinkgoT().TempDir()
+		confDir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(binDir, pluginName), []byte(plugin), 0o700)).To(Succeed())
+		client = NewDirectClient(binDir, confDir, GinkgoT().TempDir())
+	})
+
+	It("is healthy when the plugins directory exists", func() {
+		Expect(client.Healthz(context.Background())).To(Succeed())
+		Expect(NewDirectClient(filepath.Join(binDir, "missing"), confDir, "").Healthz(context.Background())).NotTo(Succeed())
+	})
+
+	It("adds the interface running the plugin", func() {
+		response, err := client.InvokeDelegate(
+			context.Background(),
+			request(CmdAdd, "net1", fmt.Sprintf(`{"cniVersion": "1.0.0", "name": "tiny-net", "type": %q}`, pluginName)),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Result.Interfaces).To(HaveLen(1))
+		Expect(*response.Result.Interfaces[0]).To(HaveField("Name", "net1"))
+		Expect(*response.Result.Interfaces[0]).To(HaveField("Sandbox", netnsPath))
+		Expect(invocations()).To(ConsistOf(
+			"ADD net1 IgnoreUnknown=true;K8S_POD_NAMESPACE=default;K8S_POD_NAME=tiny-winy-pod;K8S_POD_UID=abc-def;K8S_POD_INFRA_CONTAINER_ID=1234",
+		))
+	})
+
+	It("runs each plugin of a configuration list", func() {
+		_, err := client.InvokeDelegate(
+			context.Background(),
+			request(
+				CmdDel,
+				"net1",
+				fmt.Sprintf(`{"cniVersion": "1.0.0", "name": "tiny-net", "plugins": [{"type": %q}, {"type": %q}]}`, pluginName, pluginName),
+			),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(invocations()).To(HaveLen(2))
+	})
+
+	It("loads the configurations holding nothing but their name from the conf directory", func() {
+		Expect(os.WriteFile(
+			filepath.Join(confDir, "tiny-net.conf"),
+			[]byte(fmt.Sprintf(`{"cniVersion": "1.0.0", "name": "tiny-net", "type": %q}`, pluginName)),
+			0o600,
+		)).To(Succeed())
+
+		_, err := client.InvokeDelegate(context.Background(), request(CmdAdd, "net1", `{"name": "tiny-net"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(invocations()).To(HaveLen(1))
+	})
+
+	It("forwards the errors of the plugins", func() {
+		_, err := client.InvokeDelegate(
+			context.Background(),
+			request(CmdAdd, "net7", fmt.Sprintf(`{"cniVersion": "1.0.0", "name": "tiny-net", "type": %q}`, pluginName)),
+		)
+		var pluginErr *cnitypes.Error
+		Expect(errors.As(err, &pluginErr)).To(BeTrue())
+		Expect(pluginErr).To(Equal(&cnitypes.Error{Code: 7, Msg: "kablewit"}))
+	})
+
+	It("rejects the unsupported commands", func() {
+		_, err := client.InvokeDelegate(
+			context.Background(),
+			request("VERSION", "net1", fmt.Sprintf(`{"cniVersion": "1.0.0", "name": "tiny-net", "type": %q}`, pluginName)),
+		)
+		Expect(err).To(MatchError(`unsupported CNI command: "VERSION"`))
+	})
+})
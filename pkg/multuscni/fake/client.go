@@ -1,6 +1,7 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 
 	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
@@ -24,7 +25,7 @@ func NewFakeClient(currentStatus ...NetworkConfig) *Client {
 	return mockedClient
 }
 
-func (fc *Client) InvokeDelegate(multusRequest *multusapi.Request) (*multusapi.Response, error) {
+func (fc *Client) InvokeDelegate(_ context.Context, multusRequest *multusapi.Request) (*multusapi.Response, error) {
 	ifaceKey := key(multusRequest)
 	serverReply, wasFound := fc.requestData[ifaceKey]
 	if !wasFound {
@@ -38,11 +39,8 @@ func key(req *multusapi.Request) string {
 	if !wasFound {
 		return ""
 	}
-	ifName, wasFound := req.Env["CNI_IFNAME"]
-	if !wasFound {
-		return ""
-	}
-	return keyFromCommandAndInterfaceName(cmd, ifName)
+	// the GC and STATUS requests feature no interface
+	return keyFromCommandAndInterfaceName(cmd, req.Env["CNI_IFNAME"])
 }
 
 func keyFromCommandAndInterfaceName(cmd string, ifName string) string {
@@ -0,0 +1,69 @@
+package multuscni
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+
+	multusapi "gopkg.in/k8snetworkplumbingwg/multus-cni.v4/pkg/server/api"
+)
+
+const (
+	// validAttachmentsKey is the CNI 1.1 configuration key listing the attachments a GC must preserve
+	validAttachmentsKey = "cni.dev/valid-attachments"
+	// gcAndStatusVersion is the CNI version introducing the GC and STATUS verbs
+	gcAndStatusVersion = "1.1.0"
+)
+
+// SupportsGCAndStatus tells whether the network configuration uses a CNI version featuring the GC and
+// STATUS verbs. The configurations holding nothing but their name are loaded when invoking the plugins,
+// which is when their version is checked.
+func SupportsGCAndStatus(cniConfig []byte) (bool, error) {
+	var config struct {
+		CNIVersion string          `json:"cniVersion"`
+		Type       string          `json:"type"`
+		Plugins    json.RawMessage `json:"plugins"`
+	}
+	if err := json.Unmarshal(cniConfig, &config); err != nil {
+		return false, fmt.Errorf("failed to unmarshal the CNI configuration: %v", err)
+	}
+	if config.Type == "" && config.Plugins == nil {
+		return true, nil
+	}
+	if config.CNIVersion == "" {
+		return false, nil
+	}
+	return version.GreaterThanOrEqualTo(config.CNIVersion, gcAndStatusVersion)
+}
+
+// NewGCRequest returns the CNI GC request of the network configuration: the plugins release the
+// resources of their attachments, but the listed valid ones.
+func NewGCRequest(cniConfig []byte, validAttachments []cnitypes.GCAttachment) (*multusapi.Request, error) {
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(cniConfig, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the CNI configuration: %v", err)
+	}
+	if validAttachments == nil {
+		validAttachments = []cnitypes.GCAttachment{}
+	}
+	config[validAttachmentsKey] = validAttachments
+	gcConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the CNI GC configuration: %v", err)
+	}
+	return &multusapi.Request{
+		Env:    map[string]string{"CNI_COMMAND": CmdGC},
+		Config: gcConfig,
+	}, nil
+}
+
+// NewStatusRequest returns the CNI STATUS request of the network configuration, which fails unless
+// its plugins are ready to serve ADD requests.
+func NewStatusRequest(cniConfig []byte) *multusapi.Request {
+	return &multusapi.Request{
+		Env:    map[string]string{"CNI_COMMAND": CmdStatus},
+		Config: cniConfig,
+	}
+}
@@ -0,0 +1,60 @@
+package multuscni
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("The CNI 1.1 requests", func() {
+	const cniConfig = `{"cniVersion": "1.1.0", "name": "tiny-net", "type": "macvlan"}`
+
+	It("lists the valid attachments in the GC request configuration", func() {
+		request, err := NewGCRequest(
+			[]byte(cniConfig),
+			[]cnitypes.GCAttachment{{ContainerID: "1234", IfName: "net1"}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.Env).To(Equal(map[string]string{"CNI_COMMAND": CmdGC}))
+
+		var config map[string]interface{}
+		Expect(json.Unmarshal(request.Config, &config)).To(Succeed())
+		Expect(config).To(Equal(map[string]interface{}{
+			"cniVersion": "1.1.0",
+			"name":       "tiny-net",
+			"type":       "macvlan",
+			"cni.dev/valid-attachments": []interface{}{
+				map[string]interface{}{"containerID": "1234", "ifname": "net1"},
+			},
+		}))
+	})
+
+	It("preserves no attachment when none is valid", func() {
+		request, err := NewGCRequest([]byte(cniConfig), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(request.Config)).To(ContainSubstring(`"cni.dev/valid-attachments":[]`))
+	})
+
+	It("fails when the configuration is not valid json", func() {
+		_, err := NewGCRequest([]byte("{"), nil)
+		Expect(err).To(MatchError(HavePrefix("failed to unmarshal the CNI configuration:")))
+	})
+
+	It("forwards the configuration in the STATUS request", func() {
+		Expect(NewStatusRequest([]byte(cniConfig)).Env).To(Equal(map[string]string{"CNI_COMMAND": CmdStatus}))
+	})
+
+	DescribeTable("tells whether the configuration supports the GC and STATUS verbs", func(config string, expected bool) {
+		Expect(SupportsGCAndStatus([]byte(config))).To(Equal(expected))
+	},
+		Entry("CNI 1.1 plugin", cniConfig, true),
+		Entry("CNI 1.0 plugin", `{"cniVersion": "1.0.0", "name": "tiny-net", "type": "macvlan"}`, false),
+		Entry("plugin without version", `{"name": "tiny-net", "type": "macvlan"}`, false),
+		Entry("CNI 1.1 plugins list", `{"cniVersion": "1.1.0", "name": "tiny-net", "plugins": [{"type": "macvlan"}]}`, true),
+		Entry("CNI 0.4 plugins list", `{"cniVersion": "0.4.0", "name": "tiny-net", "plugins": [{"type": "macvlan"}]}`, false),
+		Entry("configuration referred to by name", `{"name": "tiny-net"}`, true),
+	)
+})
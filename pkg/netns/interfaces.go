@@ -0,0 +1,64 @@
+package netns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Interfaces lists the network interfaces of the network namespace at the given path.
+func Interfaces(netnsPath string) ([]net.Interface, error) {
+	var interfaces []net.Interface
+	if err := do(netnsPath, func() error {
+		var err error
+		interfaces, err = net.Interfaces()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return interfaces, nil
+}
+
+// do runs the function from a dedicated OS thread switched to the network namespace at the given
+// path. The thread is only released once switched back to its original network namespace.
+func do(netnsPath string, f func() error) error {
+	errChan := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		originalNetNS, err := os.Open(fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			errChan <- fmt.Errorf("failed to open the current network namespace: %w", err)
+			return
+		}
+		defer func() { _ = originalNetNS.Close() }()
+
+		targetNetNS, err := os.Open(netnsPath)
+		if err != nil {
+			runtime.UnlockOSThread()
+			errChan <- fmt.Errorf("failed to open the network namespace %s: %w", netnsPath, err)
+			return
+		}
+		defer func() { _ = targetNetNS.Close() }()
+
+		if err := unix.Setns(int(targetNetNS.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			errChan <- fmt.Errorf("failed to enter the network namespace %s: %w", netnsPath, err)
+			return
+		}
+
+		fErr := f()
+		if err := unix.Setns(int(originalNetNS.Fd()), unix.CLONE_NEWNET); err != nil {
+			// the thread remains locked, thus is terminated along with the goroutine
+			errChan <- fmt.Errorf("failed to restore the original network namespace: %w", err)
+			return
+		}
+		runtime.UnlockOSThread()
+		errChan <- fErr
+	}()
+	return <-errChan
+}
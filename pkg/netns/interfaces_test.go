@@ -0,0 +1,36 @@
+package netns_test
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/netns"
+)
+
+func TestNetNS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Network namespace suite")
+}
+
+var _ = Describe("Listing the interfaces of a network namespace", func() {
+	It("lists the interfaces of the network namespace", func() {
+		if os.Geteuid() != 0 {
+			Skip("entering a network namespace requires the CAP_SYS_ADMIN capability")
+		}
+
+		interfaces, err := netns.Interfaces("/proc/self/ns/net")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interfaces).To(ContainElement(WithTransform(func(iface net.Interface) bool {
+			return iface.Flags&net.FlagLoopback != 0
+		}, BeTrue())))
+	})
+
+	It("fails when the network namespace does not exist", func() {
+		_, err := netns.Interfaces("/var/run/netns/does-not-exist")
+		Expect(err).To(MatchError(ContainSubstring("failed to open the network namespace /var/run/netns/does-not-exist")))
+	})
+})
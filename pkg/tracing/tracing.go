@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the controller spans
+const TracerName = "github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller"
+
+const serviceName = "dynamic-networks-controller"
+
+// the attributes of the spans of the CNI operations
+const (
+	CNICommandKey     = attribute.Key("cni.command")
+	CNIIfNameKey      = attribute.Key("cni.ifname")
+	CNIContainerIDKey = attribute.Key("cni.container_id")
+	NetAttachDefKey   = attribute.Key("k8s.network_attachment_definition.name")
+	NetAttachDefNsKey = attribute.Key("k8s.network_attachment_definition.namespace")
+)
+
+// Setup exports the spans to the OTLP gRPC collector listening on the endpoint (host:port), and
+// propagates the trace context using the W3C traceparent headers. The returned function flushes the
+// pending spans, then stops the exporter.
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %v", err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tracerProvider.Shutdown, nil
+}
+
+// End records the error - if any - as the outcome of the span, then ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nadlisterv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/annotations"
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/logging"
+)
+
+const maxRequestSize = 3 * 1024 * 1024
+
+// PodNetworksValidator validates the updates of the networks annotation of running pods
+// before they are persisted, thus before the controller attempts to reconcile them.
+type PodNetworksValidator struct {
+	netAttachDefLister nadlisterv1.NetworkAttachmentDefinitionLister
+}
+
+// NewPodNetworksValidator returns a validator looking up the network-attachment-definitions
+// using the provided lister.
+func NewPodNetworksValidator(netAttachDefLister nadlisterv1.NetworkAttachmentDefinitionLister) *PodNetworksValidator {
+	return &PodNetworksValidator{netAttachDefLister: netAttachDefLister}
+}
+
+// Validate returns an error when the update of the networks annotation of the running pod is invalid:
+// malformed, adding attachments to network-attachment-definitions that do not exist, or requesting
+// interface names already in use. The attachments already requested are not looked up again: their
+// network-attachment-definition being deleted must not prevent the other networks from being updated.
+func (v *PodNetworksValidator) Validate(oldPod *corev1.Pod, newPod *corev1.Pod) error {
+	if newPod.Status.Phase != corev1.PodRunning {
+		return nil
+	}
+	if oldPod.GetAnnotations()[nadv1.NetworkAttachmentAnnot] == newPod.GetAnnotations()[nadv1.NetworkAttachmentAnnot] {
+		return nil
+	}
+
+	networkSelectionElements, err := annotations.PodNetworkSelectionElements(newPod)
+	if err != nil {
+		return err
+	}
+	networkStatus, err := annotations.PodDynamicNetworkStatus(newPod)
+	if err != nil {
+		return err
+	}
+	indexedNetworkStatus := annotations.IndexNetworkStatus(networkStatus)
+
+	// a malformed current annotation has every requested attachment validated as an added one
+	var previousNetworkSelectionElements []nadv1.NetworkSelectionElement
+	if elements, err := annotations.PodNetworkSelectionElements(oldPod); err == nil {
+		previousNetworkSelectionElements = elements
+	}
+	indexedPreviousNetworkSelectionElements := annotations.IndexNetworkSelectionElements(previousNetworkSelectionElements)
+
+	// the networks of the interfaces currently plugged, indexed by interface name
+	interfaceOwners := map[string]string{}
+	for i := range networkStatus {
+		interfaceOwners[networkStatus[i].Interface] = networkStatus[i].Name
+	}
+
+	requestedInterfaces := map[string]struct{}{}
+	for i := range networkSelectionElements {
+		element := networkSelectionElements[i]
+		if _, wasRequested := indexedPreviousNetworkSelectionElements[annotations.NetworkSelectionElementIndexKey(element)]; !wasRequested {
+			if err := v.validateNetAttachDefExists(element); err != nil {
+				return err
+			}
+		}
+
+		if element.InterfaceRequest == "" {
+			continue
+		}
+		if _, isDuplicate := requestedInterfaces[element.InterfaceRequest]; isDuplicate {
+			return fmt.Errorf("interface %q is requested more than once", element.InterfaceRequest)
+		}
+		requestedInterfaces[element.InterfaceRequest] = struct{}{}
+
+		if _, isPlugged := indexedNetworkStatus[annotations.NetworkSelectionElementIndexKey(element)]; isPlugged {
+			continue
+		}
+		// the attachments are added before the ones being removed are, thus the name of an
+		// interface being removed cannot be re-used in the same update.
+		if owner, isInUse := interfaceOwners[element.InterfaceRequest]; isInUse {
+			return fmt.Errorf("interface %q is already in use by network %s", element.InterfaceRequest, owner)
+		}
+	}
+	return nil
+}
+
+func (v *PodNetworksValidator) validateNetAttachDefExists(element nadv1.NetworkSelectionElement) error {
+	_, err := v.netAttachDefLister.NetworkAttachmentDefinitions(element.Namespace).Get(element.Name)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf(
+			"network-attachment-definition %s does not exist",
+			annotations.NamespacedName(element.Namespace, element.Name),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"failed to access the network-attachment-definition %s: %v",
+			annotations.NamespacedName(element.Namespace, element.Name),
+			err,
+		)
+	}
+	return nil
+}
+
+// ServeHTTP handles the pod update AdmissionReview requests.
+func (v *PodNetworksValidator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read the request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "failed to decode the admission review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = v.review(review.Request)
+	review.Response.UID = review.Request.UID
+	response, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode the admission review: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(response); err != nil {
+		klog.ErrorS(err, "failed to write the admission review response")
+	}
+}
+
+func (v *PodNetworksValidator) review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation != admissionv1.Update || request.Kind.Kind != "Pod" {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	oldPod := &corev1.Pod{}
+	if err := json.Unmarshal(request.OldObject.Raw, oldPod); err != nil {
+		return deny(fmt.Errorf("failed to decode the current pod: %v", err))
+	}
+	newPod := &corev1.Pod{}
+	if err := json.Unmarshal(request.Object.Raw, newPod); err != nil {
+		return deny(fmt.Errorf("failed to decode the updated pod: %v", err))
+	}
+
+	if err := v.Validate(oldPod, newPod); err != nil {
+		klog.InfoS("rejected the networks update", "pod", klog.KObj(newPod), "reason", err.Error())
+		return deny(err)
+	}
+	klog.V(logging.Debug).InfoS("admitted the update", "pod", klog.KObj(newPod))
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func deny(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: err.Error(),
+		},
+	}
+}
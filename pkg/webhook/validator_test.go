@@ -0,0 +1,183 @@
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nadlisterv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/multus-dynamic-networks-controller/pkg/webhook"
+)
+
+func TestWebhook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dynamic networks validating webhook suite")
+}
+
+const (
+	namespace = "ns1"
+	podName   = "tpod"
+)
+
+var _ = Describe("The pod networks validator", func() {
+	var validator *webhook.PodNetworksValidator
+
+	BeforeEach(func() {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, netName := range []string{"net1", "net2"} {
+			Expect(indexer.Add(&nadv1.NetworkAttachmentDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: netName, Namespace: namespace},
+			})).To(Succeed())
+		}
+		validator = webhook.NewPodNetworksValidator(nadlisterv1.NewNetworkAttachmentDefinitionLister(indexer))
+	})
+
+	const currentStatus = `[
+		{"name": "cluster-default", "interface": "eth0", "default": true},
+		{"name": "ns1/net1", "interface": "net1"}
+	]`
+
+	DescribeTable("admits", func(networks string) {
+		Expect(validator.Validate(
+			runningPod(`[{"name": "net1", "interface": "net1"}]`, currentStatus),
+			runningPod(networks, currentStatus),
+		)).To(Succeed())
+	},
+		Entry("adding an attachment", `[{"name": "net1", "interface": "net1"}, {"name": "net2", "interface": "net2"}]`),
+		Entry("removing an attachment", `[]`),
+		Entry(
+			"adding an attachment with valid attributes",
+			`[
+				{"name": "net1", "interface": "net1"},
+				{"name": "net2", "interface": "net2", "mac": "02:03:04:05:06:07", "ips": ["10.10.10.10/24", "fd10::10"]}
+			]`,
+		),
+		Entry("adding an attachment without interface name", `[{"name": "net1", "interface": "net1"}, {"name": "net2"}]`),
+	)
+
+	DescribeTable("rejects", func(networks string, expectedError string) {
+		Expect(validator.Validate(
+			runningPod(`[{"name": "net1", "interface": "net1"}]`, currentStatus),
+			runningPod(networks, currentStatus),
+		)).To(MatchError(ContainSubstring(expectedError)))
+	},
+		Entry("malformed annotations", `[{"name": "net1"`, "failed to parse pod Network Attachment Selection Annotation"),
+		Entry("invalid MACs", `[{"name": "net2", "interface": "net2", "mac": "02:03:04"}]`, "failed to mac"),
+		Entry("invalid CIDRs", `[{"name": "net2", "interface": "net2", "ips": ["10.10.10.10/33"]}]`, "failed to parse CIDR"),
+		Entry(
+			"networks whose network-attachment-definition does not exist",
+			`[{"name": "net3", "interface": "net3"}]`,
+			"network-attachment-definition ns1/net3 does not exist",
+		),
+		Entry(
+			"duplicate interface names",
+			`[{"name": "net1", "interface": "net1"}, {"name": "net2", "interface": "net5"}, {"name": "net2", "namespace": "ns1", "interface": "net5"}]`,
+			`interface "net5" is requested more than once`,
+		),
+		Entry(
+			"interface names used by the default network",
+			`[{"name": "net1", "interface": "net1"}, {"name": "net2", "interface": "eth0"}]`,
+			`interface "eth0" is already in use by network cluster-default`,
+		),
+		Entry(
+			"interface names used by another attachment",
+			`[{"name": "net2", "interface": "net1"}]`,
+			`interface "net1" is already in use by network ns1/net1`,
+		),
+	)
+
+	It("admits the updates not modifying the networks annotation", func() {
+		const invalidNetworks = `[{"name": "net3", "interface": "net3"}]`
+		Expect(validator.Validate(
+			runningPod(invalidNetworks, currentStatus),
+			runningPod(invalidNetworks, currentStatus),
+		)).To(Succeed())
+	})
+
+	It("admits the updates keeping an attachment whose network-attachment-definition is gone", func() {
+		Expect(validator.Validate(
+			runningPod(`[{"name": "net1", "interface": "net1"}, {"name": "net3", "interface": "net3"}]`, currentStatus),
+			runningPod(`[{"name": "net2", "interface": "net2"}, {"name": "net3", "interface": "net3"}]`, currentStatus),
+		)).To(Succeed())
+	})
+
+	It("admits the updates of pods not running", func() {
+		pendingPod := runningPod(`[{"name": "net3", "interface": "net3"}]`, "")
+		pendingPod.Status.Phase = corev1.PodPending
+		Expect(validator.Validate(runningPod("", ""), pendingPod)).To(Succeed())
+	})
+
+	When("serving admission reviews", func() {
+		review := func(oldPod, newPod *corev1.Pod) *admissionv1.AdmissionResponse {
+			server := httptest.NewServer(validator)
+			defer server.Close()
+
+			request, err := json.Marshal(&admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("abc"),
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Operation: admissionv1.Update,
+					OldObject: runtime.RawExtension{Object: oldPod},
+					Object:    runtime.RawExtension{Object: newPod},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := http.Post(server.URL, "application/json", bytes.NewReader(request)) // #nosec
+			Expect(err).NotTo(HaveOccurred())
+			defer func() { _ = resp.Body.Close() }()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			admissionReview := &admissionv1.AdmissionReview{}
+			Expect(json.NewDecoder(resp.Body).Decode(admissionReview)).To(Succeed())
+			Expect(admissionReview.Response.UID).To(Equal(types.UID("abc")))
+			return admissionReview.Response
+		}
+
+		It("allows the valid updates", func() {
+			Expect(review(
+				runningPod(`[{"name": "net1", "interface": "net1"}]`, currentStatus),
+				runningPod(`[{"name": "net1", "interface": "net1"}, {"name": "net2", "interface": "net2"}]`, currentStatus),
+			).Allowed).To(BeTrue())
+		})
+
+		It("denies the invalid updates, reporting why", func() {
+			response := review(
+				runningPod(`[{"name": "net1", "interface": "net1"}]`, currentStatus),
+				runningPod(`[{"name": "net3", "interface": "net3"}]`, currentStatus),
+			)
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(Equal("network-attachment-definition ns1/net3 does not exist"))
+		})
+	})
+})
+
+func runningPod(networks string, networkStatus string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				nadv1.NetworkAttachmentAnnot: networks,
+				nadv1.NetworkStatusAnnot:     networkStatus,
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}